@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/liju-github/EcommerceUserService/proto/user"
+	"github.com/liju-github/EcommerceUserService/service"
+)
+
+// newGateway returns an HTTP server on port that translates the JSON routes
+// declared in user.proto into calls to the gRPC server at grpcAddr, so they
+// go through the same interceptors. Calls that are not public need the end
+// user's bearer token, which is forwarded as the authorization metadata. The
+// server serves HTTPS when tlsConfig is set. The connection closes when ctx
+// is done.
+func newGateway(ctx context.Context, port, grpcAddr string, creds credentials.TransportCredentials, tlsConfig *tls.Config) (*http.Server, error) {
+	mux := runtime.NewServeMux()
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(service.GatewayUnaryInterceptor),
+	}
+	if err := user.RegisterUserServiceHandlerFromEndpoint(ctx, mux, grpcAddr, dialOptions); err != nil {
+		return nil, err
+	}
+
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}, nil
+}
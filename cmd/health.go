@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/gorm"
+
+	"github.com/liju-github/EcommerceUserService/db"
+	"github.com/liju-github/EcommerceUserService/proto/user"
+)
+
+// healthServices are the names the health service reports on: the server as
+// a whole ("") and UserService
+var healthServices = []string{"", user.UserService_ServiceDesc.ServiceName}
+
+// checkDatabaseHealth returns a worker job that pings the database and
+// reports every service as SERVING while it answers and NOT_SERVING while it
+// does not. Transitions are logged; once the health server is shut down the
+// status stays NOT_SERVING.
+func checkDatabaseHealth(healthServer *health.Server, dbConn *gorm.DB, timeout time.Duration) func(ctx context.Context) error {
+	current := healthpb.HealthCheckResponse_UNKNOWN
+	return func(ctx context.Context) error {
+		err := db.Ping(ctx, dbConn, timeout)
+
+		next := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			next = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		if next != current {
+			log.Printf("Health status is now %s", next)
+			current = next
+		}
+		for _, name := range healthServices {
+			healthServer.SetServingStatus(name, next)
+		}
+		return err
+	}
+}
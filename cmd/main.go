@@ -1,22 +1,63 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/liju-github/EcommerceUserService/certs"
 	config "github.com/liju-github/EcommerceUserService/configs"
 	"github.com/liju-github/EcommerceUserService/db"
+	"github.com/liju-github/EcommerceUserService/encryption"
+	"github.com/liju-github/EcommerceUserService/migrations"
+	model "github.com/liju-github/EcommerceUserService/models"
+	"github.com/liju-github/EcommerceUserService/postal"
 	"github.com/liju-github/EcommerceUserService/proto/user"
 	"github.com/liju-github/EcommerceUserService/repository"
 	"github.com/liju-github/EcommerceUserService/service"
 	util "github.com/liju-github/EcommerceUserService/utils"
+	"github.com/liju-github/EcommerceUserService/worker"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 	util.SetJWTSecretKey(cfg.JWTSecretKey)
+	encryption.SetKeyring(cfg.Keyring)
+	model.SetEmailProviderRules(cfg.EmailProviderRules)
+	if cfg.PincodeDatasetPath != "" {
+		if err := postal.LoadDataset(cfg.PincodeDatasetPath); err != nil {
+			log.Fatalf("Pincode dataset load failed: %v", err)
+		}
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Migrate failed: %v", err)
+		}
+		return
+	}
+
+	// Set when a server fails, to exit non-zero once the deferred cleanup
+	// below has run
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 
 	// Initialize database connection
 	dbConn, err := db.Connect(cfg)
@@ -25,9 +66,47 @@ func main() {
 	}
 	defer db.Close(dbConn)
 
+	// Refuse to run against a schema older than this build expects
+	pending, err := migrations.Pending(dbConn)
+	if err != nil {
+		log.Fatalf("Migration check failed: %v", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("Database schema is %d migrations behind, run `migrate up` first", len(pending))
+	}
+
 	// Initialize repository and service
-	userRepo := repository.NewUserRepository(dbConn)
-	userService := service.NewUserService(userRepo)
+	userRepo := repository.NewUserRepository(dbConn, cfg.DBQueryTimeout)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rekey":
+			if err := rekey(context.Background(), userRepo); err != nil {
+				log.Fatalf("Rekey failed: %v", err)
+			}
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+		return
+	}
+
+	userService := service.NewUserService(userRepo, cfg)
+
+	// Start background workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var workers worker.Group
+	workers.Go(ctx, "leaderboard refresh", cfg.LeaderboardRefreshInterval, func(ctx context.Context) error {
+		return userRepo.RefreshLeaderboards(ctx, time.Now())
+	})
+	workers.Go(ctx, "account purger", cfg.AccountPurgeInterval, func(ctx context.Context) error {
+		purged, err := userRepo.PurgeDeletedUsers(ctx, time.Now())
+		if purged > 0 {
+			log.Printf("Purged %d deleted accounts", purged)
+		}
+		return err
+	})
 
 	// Start gRPC server
 	listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
@@ -35,11 +114,109 @@ func main() {
 		log.Fatalf("Failed to start listener: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	var serverOptions []grpc.ServerOption
+	unaryInterceptors := []grpc.UnaryServerInterceptor{service.UnaryErrorInterceptor}
+	streamInterceptors := []grpc.StreamServerInterceptor{service.StreamErrorInterceptor}
+	gatewayCreds := insecure.NewCredentials()
+	var gatewayTLS *tls.Config
+	if cfg.TLSCertFile != "" {
+		reloader, err := certs.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+		if err != nil {
+			log.Fatalf("TLS setup failed: %v", err)
+		}
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(reloader.TLSConfig())))
+		workers.Go(ctx, "TLS certificate reload", cfg.TLSReloadInterval, reloader.Reload)
+		gatewayTLS = reloader.HTTPSConfig()
+
+		if cfg.GatewayPort != "" {
+			gatewayReloader, err := certs.NewReloader(cfg.GatewayTLSCertFile, cfg.GatewayTLSKeyFile, cfg.GatewayTLSCAFile)
+			if err != nil {
+				log.Fatalf("Gateway TLS setup failed: %v", err)
+			}
+			workers.Go(ctx, "gateway TLS certificate reload", cfg.TLSReloadInterval, gatewayReloader.Reload)
+			gatewayCreds = credentials.NewTLS(gatewayReloader.ClientTLSConfig(cfg.GatewayTLSServerName))
+		}
+	}
+
+	// Admin and internal methods need a client certificate identity and are
+	// refused to everyone without mutual TLS, unless explicitly opened
+	switch {
+	case cfg.TLSClientCAFile != "":
+	case cfg.AllowUnauthenticatedAdmin:
+		log.Println("WARNING: mutual TLS is off and ALLOW_UNAUTHENTICATED_ADMIN_RPCS is set, any client can call admin and internal RPCs")
+	default:
+		log.Println("Mutual TLS is off, admin and internal RPCs will be refused. Set TLS_CLIENT_CA_FILE, or ALLOW_UNAUTHENTICATED_ADMIN_RPCS=true on a trusted network, to allow them")
+	}
+	authorizer := service.NewAuthorizer(cfg.ClientIdentities, cfg.TLSClientCAFile != "", cfg.AllowUnauthenticatedAdmin)
+	unaryInterceptors = append(unaryInterceptors, authorizer.UnaryInterceptor)
+	streamInterceptors = append(streamInterceptors, authorizer.StreamInterceptor)
+	serverOptions = append(serverOptions,
+		grpc.ChainUnaryInterceptor(append(unaryInterceptors, service.UnaryValidationInterceptor)...),
+		grpc.ChainStreamInterceptor(append(streamInterceptors, service.StreamValidationInterceptor)...),
+	)
+
+	grpcServer := grpc.NewServer(serverOptions...)
 	user.RegisterUserServiceServer(grpcServer, userService)
 
+	// Report NOT_SERVING until the first database ping succeeds
+	healthServer := health.NewServer()
+	for _, name := range healthServices {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	workers.Go(ctx, "database health check", cfg.HealthCheckInterval, checkDatabaseHealth(healthServer, dbConn, cfg.DBQueryTimeout))
+
+	if cfg.GRPCReflection {
+		reflection.Register(grpcServer)
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- grpcServer.Serve(listener)
+	}()
 	log.Println("User Service is running on gRPC port: " + cfg.GRPCPort)
-	if err := grpcServer.Serve(listener); err != nil {
-		log.Fatalf("gRPC server startup failed: %v", err)
+
+	var gateway *http.Server
+	if cfg.GatewayPort != "" {
+		gateway, err = newGateway(ctx, cfg.GatewayPort, "localhost:"+cfg.GRPCPort, gatewayCreds, gatewayTLS)
+		if err != nil {
+			log.Fatalf("Gateway setup failed: %v", err)
+		}
+		go func() {
+			serve := gateway.ListenAndServe
+			if gateway.TLSConfig != nil {
+				// The certificate comes from TLSConfig
+				serve = func() error { return gateway.ListenAndServeTLS("", "") }
+			}
+			if err := serve(); err != http.ErrServerClosed {
+				serveErr <- err
+			}
+		}()
+		log.Println("HTTP gateway is running on port: " + cfg.GatewayPort)
+	}
+
+	// A second signal during shutdown kills the process as usual
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-signals:
+		log.Printf("Received %s, shutting down", sig)
+	case err := <-serveErr:
+		log.Printf("Server failed, shutting down: %v", err)
+		exitCode = 1
 	}
+	signal.Stop(signals)
+
+	// Stop being routed new calls, then let the in-flight ones finish. The
+	// gateway goes first as its requests are calls to the gRPC server.
+	healthServer.Shutdown()
+	deadline := time.Now().Add(cfg.ShutdownDrainTimeout)
+	if gateway != nil {
+		drainGateway(gateway, deadline)
+	}
+	drain(grpcServer, time.Until(deadline))
+
+	// Workers still use the database, which the deferred db.Close closes last
+	cancel()
+	workers.Wait()
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	config "github.com/liju-github/EcommerceUserService/configs"
+	"github.com/liju-github/EcommerceUserService/db"
+	"github.com/liju-github/EcommerceUserService/migrations"
+)
+
+// migrationsDir is where `migrate create` writes new migrations, relative to
+// the repository root.
+const migrationsDir = "migrations"
+
+const migrateUsage = "usage: migrate up [n] | down [n] | status | create <name>"
+
+// migrate runs a migrate subcommand:
+//
+//	migrate up [n]        apply all pending migrations, or the next n
+//	migrate down [n]      roll back the last migration, or the last n
+//	migrate status        list migrations and when they were applied
+//	migrate create <name> write a new empty migration
+func migrate(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		path, err := migrations.Create(migrationsDir, args[1])
+		if err != nil {
+			return err
+		}
+		log.Printf("Created %s", path)
+		return nil
+	}
+
+	dbConn, err := db.Connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close(dbConn)
+
+	switch args[0] {
+	case "up":
+		limit, err := migrateCount(args[1:], 0)
+		if err != nil {
+			return err
+		}
+		applied, err := migrations.Up(dbConn, limit)
+		for _, m := range applied {
+			log.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Println("Schema is up to date")
+		}
+		return err
+
+	case "down":
+		steps, err := migrateCount(args[1:], 1)
+		if err != nil {
+			return err
+		}
+		rolledBack, err := migrations.Down(dbConn, steps)
+		for _, m := range rolledBack {
+			log.Printf("Rolled back %04d_%s", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrations.StatusOf(dbConn)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, applied)
+		}
+		return nil
+
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// migrateCount parses the optional count argument of up and down.
+func migrateCount(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("count must be a positive number, got %q", args[0])
+	}
+	return n, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/liju-github/EcommerceUserService/repository"
+)
+
+const rekeyBatchSize = 500
+
+// rekey re-encrypts the stored PII under the primary key, e.g. after a new
+// key was put first in ENCRYPTION_KEYS. Older keys can be removed from the
+// keyring once it has finished.
+func rekey(ctx context.Context, repo repository.UserRepository) error {
+	rewritten, err := repo.Rekey(ctx, rekeyBatchSize)
+	if err != nil {
+		return err
+	}
+	log.Printf("Re-encrypted %d rows", rewritten)
+
+	// Leaderboards hold copies of the users' encrypted names
+	return repo.RefreshLeaderboards(ctx, time.Now())
+}
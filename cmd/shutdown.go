@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// drain stops the server from accepting new calls and waits for in-flight
+// ones to finish. Calls still running after timeout are cancelled and their
+// connections closed.
+func drain(server *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		log.Println("gRPC server drained")
+	case <-timer.C:
+		log.Printf("gRPC server not drained after %s, closing remaining connections", timeout)
+		server.Stop()
+		<-stopped
+	}
+}
+
+// drainGateway stops the gateway from accepting new requests and waits until
+// deadline for in-flight ones to finish before closing their connections.
+func drainGateway(server *http.Server, deadline time.Time) {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP gateway not drained: %v, closing remaining connections", err)
+		server.Close()
+		return
+	}
+	log.Println("HTTP gateway drained")
+}
@@ -1,20 +1,88 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"github.com/liju-github/EcommerceUserService/encryption"
+	model "github.com/liju-github/EcommerceUserService/models"
+	util "github.com/liju-github/EcommerceUserService/utils"
 )
 
 type Config struct {
-	DBUser       string
-	DBPassword   string
-	DBName       string
-	DBHost       string
-	DBPort       string
-	GRPCPort     string
-	JWTSecretKey string
+	DBDriver       string
+	DBPath         string
+	DBSSLMode      string
+	DBUser         string
+	DBPassword     string
+	DBName         string
+	DBHost         string
+	DBPort         string
+	DBQueryTimeout time.Duration
+	GRPCPort       string
+	GatewayPort    string
+	JWTSecretKey   string
+	PrivilegeTiers []model.PrivilegeTier
+
+	LeaderboardRefreshInterval time.Duration
+	PincodeDatasetPath         string
+
+	PhoneDefaultRegion string
+	PhoneOTPTTL        time.Duration
+	SMSOutboxPath      string
+
+	EmailChangeTTL     time.Duration
+	MailOutboxPath     string
+	EmailProviderRules map[string]model.EmailProviderRule
+
+	AccountDeletionGracePeriod time.Duration
+	AccountPurgeInterval       time.Duration
+
+	// Current policy versions users must have accepted
+	TermsVersion     string
+	PrivacyVersion   string
+	MarketingVersion string
+
+	// Keys for PII encryption at rest and email/phone blind indexes
+	Keyring *encryption.Keyring
+
+	// Generator of new user and address IDs
+	IDGenerator util.IDGenerator
+
+	// How often the database is pinged to update the gRPC health status
+	HealthCheckInterval time.Duration
+	// Registers gRPC server reflection, for grpcurl and similar tools
+	GRPCReflection bool
+	// How long in-flight calls may run after SIGTERM before they are cut off
+	ShutdownDrainTimeout time.Duration
+
+	// TLS for the gRPC listener, which is plaintext without a certificate.
+	// With a client CA bundle clients must present a certificate, whose SANs
+	// are mapped to service identities by ClientIdentities.
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSClientCAFile   string
+	TLSReloadInterval time.Duration
+	ClientIdentities  map[string]string
+	// Opens the admin and internal RPCs to every caller when mutual TLS is
+	// off, as before mutual TLS existed. For trusted private networks only.
+	AllowUnauthenticatedAdmin bool
+
+	// TLS of the gateway's connection to the gRPC listener. The gateway has
+	// its own client certificate, mapped to the gateway identity under
+	// mutual TLS, and verifies the listener against the CA bundle, or the
+	// system roots without one.
+	GatewayTLSCertFile   string
+	GatewayTLSKeyFile    string
+	GatewayTLSCAFile     string
+	GatewayTLSServerName string
 }
 
 func LoadConfig() Config {
@@ -22,13 +90,299 @@ func LoadConfig() Config {
 		log.Println("No .env file found")
 	}
 
+	jwtSecretKey, err := parseJWTSecret(os.Getenv("JWT_SECRET"))
+	if err != nil {
+		log.Fatalf("Invalid JWT_SECRET: %v", err)
+	}
+
+	privilegeTiers, err := parsePrivilegeTiers(os.Getenv("PRIVILEGE_TIERS"))
+	if err != nil {
+		log.Fatalf("Invalid PRIVILEGE_TIERS: %v", err)
+	}
+
+	leaderboardRefreshInterval, err := parseDuration(os.Getenv("LEADERBOARD_REFRESH_INTERVAL"), 5*time.Minute)
+	if err != nil {
+		log.Fatalf("Invalid LEADERBOARD_REFRESH_INTERVAL: %v", err)
+	}
+
+	phoneOTPTTL, err := parseDuration(os.Getenv("PHONE_OTP_TTL"), 10*time.Minute)
+	if err != nil {
+		log.Fatalf("Invalid PHONE_OTP_TTL: %v", err)
+	}
+
+	emailChangeTTL, err := parseDuration(os.Getenv("EMAIL_CHANGE_TTL"), 24*time.Hour)
+	if err != nil {
+		log.Fatalf("Invalid EMAIL_CHANGE_TTL: %v", err)
+	}
+
+	emailProviderRules, err := parseEmailProviderRules(os.Getenv("EMAIL_PROVIDER_RULES"))
+	if err != nil {
+		log.Fatalf("Invalid EMAIL_PROVIDER_RULES: %v", err)
+	}
+
+	accountDeletionGracePeriod, err := parseDuration(os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"), 30*24*time.Hour)
+	if err != nil {
+		log.Fatalf("Invalid ACCOUNT_DELETION_GRACE_PERIOD: %v", err)
+	}
+
+	accountPurgeInterval, err := parseDuration(os.Getenv("ACCOUNT_PURGE_INTERVAL"), time.Hour)
+	if err != nil {
+		log.Fatalf("Invalid ACCOUNT_PURGE_INTERVAL: %v", err)
+	}
+
+	keyring, err := encryption.ParseKeyring(os.Getenv("ENCRYPTION_KEYS"), os.Getenv("BLIND_INDEX_KEY"))
+	if err != nil {
+		log.Fatalf("Invalid ENCRYPTION_KEYS or BLIND_INDEX_KEY: %v", err)
+	}
+
+	idGenerator, err := util.NewIDGenerator(getEnvDefault("ID_FORMAT", util.IDFormatULID))
+	if err != nil {
+		log.Fatalf("Invalid ID_FORMAT: %v", err)
+	}
+
+	dbQueryTimeout, err := parseDuration(os.Getenv("DB_QUERY_TIMEOUT"), 5*time.Second)
+	if err != nil {
+		log.Fatalf("Invalid DB_QUERY_TIMEOUT: %v", err)
+	}
+
+	healthCheckInterval, err := parseDuration(os.Getenv("HEALTH_CHECK_INTERVAL"), 10*time.Second)
+	if err != nil {
+		log.Fatalf("Invalid HEALTH_CHECK_INTERVAL: %v", err)
+	}
+
+	grpcReflection, err := parseBool(os.Getenv("GRPC_REFLECTION"), false)
+	if err != nil {
+		log.Fatalf("Invalid GRPC_REFLECTION: %v", err)
+	}
+
+	shutdownDrainTimeout, err := parseDuration(os.Getenv("SHUTDOWN_DRAIN_TIMEOUT"), 20*time.Second)
+	if err != nil {
+		log.Fatalf("Invalid SHUTDOWN_DRAIN_TIMEOUT: %v", err)
+	}
+
+	tlsCertFile, tlsKeyFile, tlsClientCAFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"), os.Getenv("TLS_CLIENT_CA_FILE")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		log.Fatalf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if tlsClientCAFile != "" && tlsCertFile == "" {
+		log.Fatalf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+
+	gatewayTLSCertFile, gatewayTLSKeyFile := os.Getenv("GATEWAY_TLS_CERT_FILE"), os.Getenv("GATEWAY_TLS_KEY_FILE")
+	gatewayTLSCAFile := os.Getenv("GATEWAY_TLS_CA_FILE")
+	if (gatewayTLSCertFile == "") != (gatewayTLSKeyFile == "") {
+		log.Fatalf("GATEWAY_TLS_CERT_FILE and GATEWAY_TLS_KEY_FILE must be set together")
+	}
+	if (gatewayTLSCertFile != "" || gatewayTLSCAFile != "") && tlsCertFile == "" {
+		log.Fatalf("GATEWAY_TLS_CERT_FILE and GATEWAY_TLS_CA_FILE require TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if tlsClientCAFile != "" && os.Getenv("GATEWAY_PORT") != "" && gatewayTLSCertFile == "" {
+		log.Fatalf("GATEWAY_PORT with TLS_CLIENT_CA_FILE requires GATEWAY_TLS_CERT_FILE and GATEWAY_TLS_KEY_FILE")
+	}
+
+	tlsReloadInterval, err := parseDuration(os.Getenv("TLS_RELOAD_INTERVAL"), time.Minute)
+	if err != nil {
+		log.Fatalf("Invalid TLS_RELOAD_INTERVAL: %v", err)
+	}
+
+	allowUnauthenticatedAdmin, err := parseBool(os.Getenv("ALLOW_UNAUTHENTICATED_ADMIN_RPCS"), false)
+	if err != nil {
+		log.Fatalf("Invalid ALLOW_UNAUTHENTICATED_ADMIN_RPCS: %v", err)
+	}
+	if allowUnauthenticatedAdmin && tlsClientCAFile != "" {
+		log.Fatalf("ALLOW_UNAUTHENTICATED_ADMIN_RPCS cannot be used with TLS_CLIENT_CA_FILE")
+	}
+
+	clientIdentities, err := parseClientIdentities(os.Getenv("TLS_CLIENT_IDENTITIES"))
+	if err != nil {
+		log.Fatalf("Invalid TLS_CLIENT_IDENTITIES: %v", err)
+	}
+
 	return Config{
-		DBUser:       os.Getenv("DB_USER"),
-		DBPassword:   os.Getenv("DB_PASSWORD"),
-		DBName:       os.Getenv("DB_NAME"),
-		DBHost:       os.Getenv("DB_HOST"),
-		DBPort:       os.Getenv("DB_PORT"),
-		GRPCPort:     os.Getenv("GRPC_PORT"),
-		JWTSecretKey: os.Getenv("JWT_SECRET"),
+		DBDriver:       getEnvDefault("DB_DRIVER", "sqlite"),
+		DBPath:         getEnvDefault("SQLITE_PATH", "./db.sqlite3"),
+		DBSSLMode:      getEnvDefault("DB_SSLMODE", "disable"),
+		DBUser:         os.Getenv("DB_USER"),
+		DBPassword:     os.Getenv("DB_PASSWORD"),
+		DBName:         os.Getenv("DB_NAME"),
+		DBHost:         os.Getenv("DB_HOST"),
+		DBPort:         os.Getenv("DB_PORT"),
+		DBQueryTimeout: dbQueryTimeout,
+		GRPCPort:       os.Getenv("GRPC_PORT"),
+		GatewayPort:    os.Getenv("GATEWAY_PORT"),
+		JWTSecretKey:   jwtSecretKey,
+		PrivilegeTiers: privilegeTiers,
+
+		LeaderboardRefreshInterval: leaderboardRefreshInterval,
+		PincodeDatasetPath:         os.Getenv("PINCODE_DATASET_PATH"),
+
+		PhoneDefaultRegion: getEnvDefault("PHONE_DEFAULT_REGION", "IN"),
+		PhoneOTPTTL:        phoneOTPTTL,
+		SMSOutboxPath:      os.Getenv("SMS_OUTBOX_PATH"),
+
+		EmailChangeTTL:     emailChangeTTL,
+		MailOutboxPath:     os.Getenv("MAIL_OUTBOX_PATH"),
+		EmailProviderRules: emailProviderRules,
+
+		AccountDeletionGracePeriod: accountDeletionGracePeriod,
+		AccountPurgeInterval:       accountPurgeInterval,
+
+		TermsVersion:     getEnvDefault("TERMS_VERSION", "1"),
+		PrivacyVersion:   getEnvDefault("PRIVACY_VERSION", "1"),
+		MarketingVersion: getEnvDefault("MARKETING_CONSENT_VERSION", "1"),
+
+		Keyring: keyring,
+
+		IDGenerator: idGenerator,
+
+		HealthCheckInterval: healthCheckInterval,
+		GRPCReflection:      grpcReflection,
+
+		ShutdownDrainTimeout: shutdownDrainTimeout,
+
+		TLSCertFile:       tlsCertFile,
+		TLSKeyFile:        tlsKeyFile,
+		TLSClientCAFile:   tlsClientCAFile,
+		TLSReloadInterval: tlsReloadInterval,
+		ClientIdentities:  clientIdentities,
+
+		AllowUnauthenticatedAdmin: allowUnauthenticatedAdmin,
+
+		GatewayTLSCertFile:   gatewayTLSCertFile,
+		GatewayTLSKeyFile:    gatewayTLSKeyFile,
+		GatewayTLSCAFile:     gatewayTLSCAFile,
+		GatewayTLSServerName: os.Getenv("GATEWAY_TLS_SERVER_NAME"),
+	}
+}
+
+// getEnvDefault returns the environment variable or def when it is unset or empty.
+func getEnvDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// parseDuration parses a Go duration string such as "5m", falling back to
+// def when the value is empty.
+func parseDuration(value string, def time.Duration) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive, got %s", d)
+	}
+	return d, nil
+}
+
+// parseBool parses a boolean such as "true" or "0", falling back to def when
+// the value is empty.
+func parseBool(value string, def bool) (bool, error) {
+	if strings.TrimSpace(value) == "" {
+		return def, nil
+	}
+	return strconv.ParseBool(strings.TrimSpace(value))
+}
+
+// minJWTSecretLength is the shortest JWT_SECRET accepted, the size of the
+// HS256 hash
+const minJWTSecretLength = 32
+
+// parseJWTSecret checks the key tokens are signed and verified with. HS256
+// accepts any key, even an empty one, with which anyone could forge tokens.
+func parseJWTSecret(value string) (string, error) {
+	if value == "" {
+		return "", errors.New("must be set")
+	}
+	if len(value) < minJWTSecretLength {
+		return "", fmt.Errorf("must be at least %d bytes, got %d", minJWTSecretLength, len(value))
+	}
+	return value, nil
+}
+
+// parsePrivilegeTiers reads a comma separated list of name:threshold pairs,
+// e.g. "upvote:15,comment:50,moderate:10000". An empty value selects the
+// default table.
+func parsePrivilegeTiers(value string) ([]model.PrivilegeTier, error) {
+	if strings.TrimSpace(value) == "" {
+		return model.DefaultPrivilegeTiers, nil
+	}
+
+	var tiers []model.PrivilegeTier
+	for _, entry := range strings.Split(value, ",") {
+		name, threshold, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected name:threshold, got %q", entry)
+		}
+		minReputation, err := strconv.ParseInt(strings.TrimSpace(threshold), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold for %q: %w", name, err)
+		}
+		tiers = append(tiers, model.PrivilegeTier{Name: strings.TrimSpace(name), MinReputation: int32(minReputation)})
+	}
+	return tiers, nil
+}
+
+// parseEmailProviderRules reads semicolon separated domain:rules entries,
+// e.g. "gmail.com:ignore_dots,strip_subaddress;googlemail.com:ignore_dots,
+// strip_subaddress,domain=gmail.com". An empty value selects the default
+// rules and "none" disables them.
+func parseEmailProviderRules(value string) (map[string]model.EmailProviderRule, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "":
+		return model.DefaultEmailProviderRules, nil
+	case "none":
+		return map[string]model.EmailProviderRule{}, nil
+	}
+
+	rules := make(map[string]model.EmailProviderRule)
+	for _, entry := range strings.Split(value, ";") {
+		domain, flags, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if !ok || domain == "" {
+			return nil, fmt.Errorf("expected domain:rules, got %q", entry)
+		}
+
+		var rule model.EmailProviderRule
+		for _, flag := range strings.Split(flags, ",") {
+			flag = strings.TrimSpace(flag)
+			switch {
+			case flag == "ignore_dots":
+				rule.IgnoreDots = true
+			case flag == "strip_subaddress":
+				rule.StripSubaddress = true
+			case strings.HasPrefix(flag, "domain="):
+				rule.CanonicalDomain = strings.ToLower(strings.TrimPrefix(flag, "domain="))
+			default:
+				return nil, fmt.Errorf("unknown rule %q for %s", flag, domain)
+			}
+		}
+		rules[domain] = rule
+	}
+	return rules, nil
+}
+
+// parseClientIdentities reads a comma separated list of san=identity pairs,
+// e.g. "admin.internal=admin,spiffe://ecommerce/gateway=gateway". The SAN is
+// a DNS name or URI of the client certificate.
+func parseClientIdentities(value string) (map[string]string, error) {
+	identities := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return identities, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		san, identity, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		san, identity = strings.TrimSpace(san), strings.TrimSpace(identity)
+		if !ok || san == "" || identity == "" {
+			return nil, fmt.Errorf("expected san=identity, got %q", entry)
+		}
+		identities[san] = identity
 	}
+	return identities, nil
 }
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseJWTSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"empty", "", true},
+		{"too short", strings.Repeat("x", minJWTSecretLength-1), true},
+		{"minimum length", strings.Repeat("x", minJWTSecretLength), false},
+		{"long", "q3Yx1Jb9N2kQ7Lw0dT5VfZ8uHcRm4sAe6GiPoKyBnXj=", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJWTSecret(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJWTSecret() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.value {
+				t.Errorf("parseJWTSecret() = %q, want %q", got, tt.value)
+			}
+		})
+	}
+}
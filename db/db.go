@@ -1,23 +1,57 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"github.com/liju-github/EcommerceUserService/configs"
-	"github.com/liju-github/EcommerceUserService/models"
 )
 
-// Connect establishes a connection to the SQLite database using GORM and configures connection pool settings.
+// Supported values of DB_DRIVER
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// poolSettings configures the connection pool of a driver.
+type poolSettings struct {
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+}
+
+// SQLite serializes writers, so a single connection avoids "database is
+// locked" errors. MySQL connections are recycled before the server's default
+// wait_timeout would close them.
+var driverPools = map[string]poolSettings{
+	DriverSQLite:   {maxOpenConns: 1, maxIdleConns: 1},
+	DriverPostgres: {maxOpenConns: 25, maxIdleConns: 5, connMaxLifetime: 5 * time.Minute, connMaxIdleTime: 5 * time.Minute},
+	DriverMySQL:    {maxOpenConns: 25, maxIdleConns: 5, connMaxLifetime: 3 * time.Minute, connMaxIdleTime: time.Minute},
+}
+
+// Connect opens the database selected by DB_DRIVER using GORM and configures
+// connection pool settings for it.
 // Returns a GORM database instance or an error if the connection fails.
 func Connect(cfg config.Config) (*gorm.DB, error) {
-	dsn := "./db.sqlite3" // Database path; can be adjusted in the config if needed
+	dialector, err := dialectorFor(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	// TranslateError reports unique violations as gorm.ErrDuplicatedKey on every driver
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -28,21 +62,70 @@ func Connect(cfg config.Config) (*gorm.DB, error) {
 	}
 
 	// Configure database connection pool settings
-	sqlDB.SetMaxOpenConns(25)
-	sqlDB.SetMaxIdleConns(5)
-	sqlDB.SetConnMaxLifetime(5 * time.Minute)
-	sqlDB.SetConnMaxIdleTime(5 * time.Minute)
-
-	// Auto-migrate database schema for all models
-	if err := db.AutoMigrate(&model.User{}); err != nil {
-		return nil, fmt.Errorf("auto-migration failed: %w", err)
-	}
+	pool := driverPools[cfg.DBDriver]
+	sqlDB.SetMaxOpenConns(pool.maxOpenConns)
+	sqlDB.SetMaxIdleConns(pool.maxIdleConns)
+	sqlDB.SetConnMaxLifetime(pool.connMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.connMaxIdleTime)
 
-	log.Println("Connected to SQLite database and schema migrated")
+	log.Printf("Connected to %s database", cfg.DBDriver)
 	return db, nil
 }
 
-// Close terminates the SQLite database connection safely.
+// dialectorFor builds the GORM dialector of the configured driver. The server
+// based drivers take their DSN from the DB_* settings; SQLite opens SQLITE_PATH.
+func dialectorFor(cfg config.Config) (gorm.Dialector, error) {
+	switch cfg.DBDriver {
+	case DriverSQLite:
+		return sqlite.Open(cfg.DBPath), nil
+
+	case DriverPostgres:
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.DBUser, cfg.DBPassword),
+			Host:     net.JoinHostPort(valueOr(cfg.DBHost, "localhost"), valueOr(cfg.DBPort, "5432")),
+			Path:     "/" + cfg.DBName,
+			RawQuery: url.Values{"sslmode": {cfg.DBSSLMode}, "TimeZone": {"UTC"}}.Encode(),
+		}
+		return postgres.Open(dsn.String()), nil
+
+	case DriverMySQL:
+		dsn := mysqldriver.NewConfig()
+		dsn.User = cfg.DBUser
+		dsn.Passwd = cfg.DBPassword
+		dsn.Net = "tcp"
+		dsn.Addr = net.JoinHostPort(valueOr(cfg.DBHost, "localhost"), valueOr(cfg.DBPort, "3306"))
+		dsn.DBName = cfg.DBName
+		dsn.ParseTime = true
+		dsn.Loc = time.UTC
+		dsn.Params = map[string]string{"charset": "utf8mb4"}
+		return mysql.Open(dsn.FormatDSN()), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q", cfg.DBDriver)
+	}
+}
+
+func valueOr(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// Ping checks that the database is reachable, giving up after timeout.
+func Ping(ctx context.Context, db *gorm.DB, timeout time.Duration) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve SQL DB instance: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// Close terminates the database connection safely.
 func Close(db *gorm.DB) {
 	if db == nil {
 		log.Println("No active database connection to close.")
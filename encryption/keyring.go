@@ -0,0 +1,216 @@
+// Package encryption provides envelope encryption for PII stored in the
+// database and keyed blind indexes for looking encrypted values up.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// keySize is the length of key encryption keys, data keys and the blind
+// index key (AES-256 / HMAC-SHA256).
+const keySize = 32
+
+// prefix marks an encrypted value. Values without it are legacy plain text
+// and are returned as is until they are re-encrypted.
+const prefix = "enc:v1:"
+
+var (
+	ErrUnknownKey       = errors.New("encrypted value uses a key that is not in the keyring")
+	ErrMalformedValue   = errors.New("malformed encrypted value")
+	ErrKeyringNotLoaded = errors.New("encryption keyring is not configured")
+)
+
+// Keyring holds the key encryption keys and the blind index key. The primary
+// key encrypts new values; the others are only kept to decrypt values written
+// before a rotation.
+type Keyring struct {
+	primary  string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+var keyring *Keyring
+
+// SetKeyring installs the keyring used by Encrypt, Decrypt and BlindIndex.
+func SetKeyring(k *Keyring) {
+	keyring = k
+}
+
+// ParseKeyring reads a comma separated list of id:base64key pairs, e.g.
+// "2025-01:...,2024-06:...". The first key is the primary one. indexKey is
+// the base64 encoded HMAC key for blind indexes.
+func ParseKeyring(keys, indexKey string) (*Keyring, error) {
+	if strings.TrimSpace(keys) == "" {
+		return nil, errors.New("no encryption keys configured")
+	}
+
+	k := &Keyring{keys: map[string]cipher.AEAD{}}
+	for _, entry := range strings.Split(keys, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("expected id:key, got %q", entry)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+
+	index, err := decodeKey(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blind index key: %w", err)
+	}
+	k.indexKey = index
+	return k, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext under a fresh data key, which is itself sealed by
+// the primary key. aad binds the value to where it is stored (the column
+// name) so ciphertexts cannot be moved between columns. Empty strings are
+// stored as is.
+func Encrypt(plaintext, aad string) (string, error) {
+	if keyring == nil {
+		return "", ErrKeyringNotLoaded
+	}
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrappedKey, err := seal(keyring.keys[keyring.primary], dataKey, []byte(keyring.primary))
+	if err != nil {
+		return "", err
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataAEAD, []byte(plaintext), []byte(aad))
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + keyring.primary + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt with the same aad. Values that
+// were never encrypted are returned unchanged.
+func Decrypt(value, aad string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	if keyring == nil {
+		return "", ErrKeyringNotLoaded
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformedValue
+	}
+	keyID := parts[0]
+	kek, ok := keyring.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+
+	dataKey, err := open(kek, wrappedKey, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataAEAD, sealed, []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex returns a keyed HMAC of value that can be stored next to the
+// encrypted value and queried for equality. Empty values have an empty index.
+func BlindIndex(value string) (string, error) {
+	if keyring == nil {
+		return "", ErrKeyringNotLoaded
+	}
+	if value == "" {
+		return "", nil
+	}
+	mac := hmac.New(sha256.New, keyring.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformedValue
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,47 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", Serializer{})
+}
+
+// Serializer encrypts string fields tagged `gorm:"serializer:encrypted"` on
+// write and decrypts them on read. The column name is used as associated
+// data.
+type Serializer struct{}
+
+// Scan implements schema.SerializerInterface
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for encrypted field %s", dbValue, field.Name)
+	}
+
+	plaintext, err := Decrypt(value, field.DBName)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", field.Name, err)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+// Value implements schema.SerializerValuerInterface
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %T for encrypted field %s", fieldValue, field.Name)
+	}
+	return Encrypt(plaintext, field.DBName)
+}
@@ -0,0 +1,156 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The schema as AutoMigrate left it before versioned migrations. Databases
+// created by earlier releases already have these tables, which AutoMigrate
+// completes with any missing columns and indexes.
+
+type baselineUser struct {
+	ID                    string
+	Email                 string
+	EmailIndex            string `gorm:"index"`
+	PasswordHash          string
+	Name                  string
+	StreetName            string
+	Locality              string
+	State                 string
+	Pincode               string
+	PhoneNumber           string
+	PhoneIndex            string `gorm:"index"`
+	Reputation            int32
+	VerificationCode      string
+	IsBanned              bool
+	IsVerified            bool
+	IsPhoneVerified       bool
+	PendingPhoneNumber    string
+	PhoneOTPHash          string
+	PhoneOTPExpiresAt     time.Time
+	PhoneOTPAttempts      int
+	PendingEmail          string
+	EmailChangeCodeHash   string
+	EmailChangeCancelHash string
+	EmailChangeExpiresAt  time.Time
+	EmailChangeAttempts   int
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	DeletedAt             gorm.DeletedAt `gorm:"index"`
+	PurgeAfter            *time.Time
+	PurgedAt              *time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineReputationEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"index"`
+	Delta     int32
+	Reason    string
+	SourceID  string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (baselineReputationEvent) TableName() string { return "reputation_events" }
+
+type baselineLeaderboardEntry struct {
+	Period      string `gorm:"primaryKey"`
+	UserID      string `gorm:"primaryKey"`
+	Name        string
+	State       string `gorm:"index"`
+	Locality    string `gorm:"index"`
+	Points      int64  `gorm:"index"`
+	RefreshedAt time.Time
+}
+
+func (baselineLeaderboardEntry) TableName() string { return "leaderboard_entries" }
+
+type baselineUserBadge struct {
+	UserID    string `gorm:"primaryKey"`
+	BadgeID   string `gorm:"primaryKey"`
+	Tier      string `gorm:"index"`
+	AwardedAt time.Time
+}
+
+func (baselineUserBadge) TableName() string { return "user_badges" }
+
+type baselineAddress struct {
+	ID                string `gorm:"primaryKey"`
+	UserID            string `gorm:"index"`
+	Label             string
+	RecipientName     string
+	Phone             string
+	StreetName        string
+	Locality          string
+	Landmark          string
+	State             string
+	Pincode           string
+	IsDefaultShipping bool
+	IsDefaultBilling  bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (baselineAddress) TableName() string { return "addresses" }
+
+type baselineAuditLog struct {
+	ID           uint `gorm:"primaryKey"`
+	ActorID      string
+	Action       string `gorm:"index"`
+	TargetUserID string `gorm:"index"`
+	Metadata     string
+	CreatedAt    time.Time
+}
+
+func (baselineAuditLog) TableName() string { return "audit_logs" }
+
+type baselineLoginEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"index"`
+	Success   bool
+	IPAddress string
+	UserAgent string
+	CreatedAt time.Time
+}
+
+func (baselineLoginEvent) TableName() string { return "login_events" }
+
+type baselineConsent struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      string `gorm:"index"`
+	PolicyType  string
+	Version     string
+	AcceptedAt  time.Time
+	WithdrawnAt *time.Time
+	SourceIP    string
+	Source      string
+}
+
+func (baselineConsent) TableName() string { return "consents" }
+
+var baselineTables = []interface{}{
+	&baselineUser{},
+	&baselineReputationEvent{},
+	&baselineLeaderboardEntry{},
+	&baselineUserBadge{},
+	&baselineAddress{},
+	&baselineAuditLog{},
+	&baselineLoginEvent{},
+	&baselineConsent{},
+}
+
+func init() {
+	Register(Migration{
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baselineTables...)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(baselineTables...)
+		},
+	})
+}
@@ -0,0 +1,171 @@
+package migrations
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/liju-github/EcommerceUserService/encryption"
+)
+
+// Users created before timestamps, the address book and field-level
+// encryption existed are completed here: created_at is set, their single
+// address is copied into the address book and their PII is encrypted.
+// The rows are read and written as plain columns, so values are encrypted
+// with the column name as associated data just like the GORM serializer does.
+
+type legacyUser struct {
+	ID                 string
+	Email              string
+	Name               string
+	StreetName         string
+	Locality           string
+	State              string
+	Pincode            string
+	PhoneNumber        string
+	PendingPhoneNumber string
+	PendingEmail       string
+}
+
+func init() {
+	Register(Migration{
+		Version: 2,
+		Name:    "backfill_legacy_users",
+		Up: func(tx *gorm.DB) error {
+			if err := backfillUserTimestamps(tx); err != nil {
+				return err
+			}
+			if err := migrateLegacyAddresses(tx); err != nil {
+				return err
+			}
+			return encryptLegacyUsers(tx)
+		},
+		// The backfilled data is valid in the baseline schema as well
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+
+// backfillUserTimestamps sets created_at and updated_at on users created
+// before the columns existed.
+func backfillUserTimestamps(tx *gorm.DB) error {
+	now := time.Now()
+	return tx.Table("users").Where("created_at IS NULL").
+		UpdateColumns(map[string]interface{}{"created_at": now, "updated_at": now}).Error
+}
+
+// migrateLegacyAddresses copies the single address stored on each user into
+// their address book as the default shipping and billing address. Users that
+// already have an address book are skipped.
+func migrateLegacyAddresses(tx *gorm.DB) error {
+	var users []legacyUser
+	if err := tx.Table("users").
+		Where("deleted_at IS NULL").
+		Where("(street_name <> '' OR locality <> '' OR state <> '' OR pincode <> '')").
+		Where("id NOT IN (?)", tx.Table("addresses").Select("user_id")).
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, user := range users {
+		recipientName, err := reencrypt(user.Name, "name", "recipient_name")
+		if err != nil {
+			return err
+		}
+		phone, err := reencrypt(user.PhoneNumber, "phone_number", "phone")
+		if err != nil {
+			return err
+		}
+		streetName, err := reencrypt(user.StreetName, "street_name", "street_name")
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Table("addresses").Create(map[string]interface{}{
+			"id":                  "addr_" + strings.TrimPrefix(user.ID, "usr_"),
+			"user_id":             user.ID,
+			"label":               "Home",
+			"recipient_name":      recipientName,
+			"phone":               phone,
+			"street_name":         streetName,
+			"locality":            user.Locality,
+			"landmark":            "",
+			"state":               user.State,
+			"pincode":             user.Pincode,
+			"is_default_shipping": true,
+			"is_default_billing":  true,
+			"created_at":          now,
+			"updated_at":          now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(users) > 0 {
+		log.Printf("Migrated %d legacy addresses into the address book", len(users))
+	}
+	return nil
+}
+
+// encryptLegacyUsers encrypts the PII of users stored before field-level
+// encryption and computes their blind indexes so email lookups find them.
+func encryptLegacyUsers(tx *gorm.DB) error {
+	var users []legacyUser
+	if err := tx.Table("users").Where("email_index IS NULL OR email_index = ''").Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		columns := map[string]interface{}{}
+		plaintexts := map[string]string{}
+		for column, value := range map[string]string{
+			"email":                user.Email,
+			"name":                 user.Name,
+			"street_name":          user.StreetName,
+			"phone_number":         user.PhoneNumber,
+			"pending_phone_number": user.PendingPhoneNumber,
+			"pending_email":        user.PendingEmail,
+		} {
+			plaintext, err := encryption.Decrypt(value, column)
+			if err != nil {
+				return err
+			}
+			encrypted, err := encryption.Encrypt(plaintext, column)
+			if err != nil {
+				return err
+			}
+			plaintexts[column] = plaintext
+			columns[column] = encrypted
+		}
+		for column, plaintext := range map[string]string{"email_index": plaintexts["email"], "phone_index": plaintexts["phone_number"]} {
+			index, err := encryption.BlindIndex(plaintext)
+			if err != nil {
+				return err
+			}
+			columns[column] = index
+		}
+
+		if err := tx.Table("users").Where("id = ?", user.ID).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(users) > 0 {
+		log.Printf("Encrypted the PII of %d legacy users", len(users))
+	}
+	return nil
+}
+
+// reencrypt moves a value stored in one column to another, encrypting it with
+// the associated data of the target column.
+func reencrypt(value, fromColumn, toColumn string) (string, error) {
+	plaintext, err := encryption.Decrypt(value, fromColumn)
+	if err != nil {
+		return "", err
+	}
+	return encryption.Encrypt(plaintext, toColumn)
+}
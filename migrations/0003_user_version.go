@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Profile updates are guarded by a version that every write bumps. Existing
+// users start at version 1.
+
+type versionedUser struct {
+	Version int64 `gorm:"not null;default:1"`
+}
+
+func (versionedUser) TableName() string { return "users" }
+
+func init() {
+	Register(Migration{
+		Version: 3,
+		Name:    "user_version",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&versionedUser{}, "Version") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&versionedUser{}, "Version")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&versionedUser{}, "Version")
+		},
+	})
+}
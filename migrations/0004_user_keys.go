@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Users are keyed by id and their email blind index is unique. Tables made
+// by old releases may lack the primary key, and duplicate emails have to be
+// resolved by hand before the unique index can be built.
+
+type keyedUser struct {
+	ID         string `gorm:"primaryKey"`
+	EmailIndex string `gorm:"uniqueIndex"`
+}
+
+func (keyedUser) TableName() string { return "users" }
+
+type unkeyedUser struct {
+	EmailIndex string `gorm:"index"`
+}
+
+func (unkeyedUser) TableName() string { return "users" }
+
+const userEmailIndexName = "idx_users_email_index"
+
+func init() {
+	Register(Migration{
+		Version: 4,
+		Name:    "user_keys",
+		Up: func(tx *gorm.DB) error {
+			if err := ensureUserPrimaryKey(tx); err != nil {
+				return err
+			}
+
+			var duplicates int64
+			if err := tx.Table("(?) AS d", tx.Table("users").Select("email_index").
+				Group("email_index").Having("COUNT(*) > 1")).Count(&duplicates).Error; err != nil {
+				return err
+			}
+			if duplicates > 0 {
+				return fmt.Errorf("%d email addresses belong to more than one user, merge or purge them first", duplicates)
+			}
+
+			if tx.Migrator().HasIndex(&unkeyedUser{}, userEmailIndexName) {
+				if err := tx.Migrator().DropIndex(&unkeyedUser{}, userEmailIndexName); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&keyedUser{}, userEmailIndexName)
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&keyedUser{}, userEmailIndexName); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&unkeyedUser{}, userEmailIndexName)
+		},
+	})
+}
+
+// ensureUserPrimaryKey makes users.id the primary key if it is not already.
+// SQLite cannot add one to an existing table, but every SQLite database this
+// service created has it.
+func ensureUserPrimaryKey(tx *gorm.DB) error {
+	columns, err := tx.Migrator().ColumnTypes(&keyedUser{})
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if column.Name() != "id" {
+			continue
+		}
+		if isKey, ok := column.PrimaryKey(); !ok || isKey {
+			return nil
+		}
+	}
+
+	if tx.Dialector.Name() == "sqlite" {
+		return fmt.Errorf("users.id is not a primary key and SQLite cannot add one, rebuild the table")
+	}
+	// MySQL cannot key TEXT columns, so the column is narrowed first
+	if err := tx.Migrator().AlterColumn(&keyedUser{}, "ID"); err != nil {
+		return err
+	}
+	return tx.Exec("ALTER TABLE users ADD PRIMARY KEY (id)").Error
+}
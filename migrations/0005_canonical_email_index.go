@@ -0,0 +1,113 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/liju-github/EcommerceUserService/encryption"
+)
+
+// Email blind indexes are computed over the canonical address, so the
+// unique index also catches addresses differing only in case or in parts
+// the provider ignores. Accounts that now collide have to be merged or
+// purged by hand first. The migration applies the default provider rules as
+// they were when it was written; `rekey` recomputes the indexes with the
+// configured rules.
+
+type indexedEmail struct {
+	ID    string
+	Email string
+}
+
+type canonicalEmailRule struct {
+	ignoreDots      bool
+	stripSubaddress bool
+	canonicalDomain string
+}
+
+var canonicalEmailRules = map[string]canonicalEmailRule{
+	"gmail.com":      {ignoreDots: true, stripSubaddress: true},
+	"googlemail.com": {ignoreDots: true, stripSubaddress: true, canonicalDomain: "gmail.com"},
+}
+
+// canonicalEmail lowercases an address and applies its provider's rule
+func canonicalEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+
+	rule, ok := canonicalEmailRules[domain]
+	if !ok {
+		return email
+	}
+	if rule.stripSubaddress {
+		if plus := strings.Index(local, "+"); plus > 0 {
+			local = local[:plus]
+		}
+	}
+	if rule.ignoreDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	if rule.canonicalDomain != "" {
+		domain = rule.canonicalDomain
+	}
+	return local + "@" + domain
+}
+
+func init() {
+	Register(Migration{
+		Version: 5,
+		Name:    "canonical_email_index",
+		Up: func(tx *gorm.DB) error {
+			var users []indexedEmail
+			if err := tx.Table("users").Select("id", "email").Order("id").Find(&users).Error; err != nil {
+				return err
+			}
+
+			indexes := make(map[string]string, len(users))
+			owners := make(map[string][]string)
+			for _, user := range users {
+				email, err := encryption.Decrypt(user.Email, "email")
+				if err != nil {
+					return fmt.Errorf("failed to decrypt the email of %s: %w", user.ID, err)
+				}
+				index, err := encryption.BlindIndex(canonicalEmail(email))
+				if err != nil {
+					return err
+				}
+				indexes[user.ID] = index
+				owners[index] = append(owners[index], user.ID)
+			}
+
+			var collisions []string
+			for _, ids := range owners {
+				if len(ids) > 1 {
+					collisions = append(collisions, strings.Join(ids, "="))
+				}
+			}
+			if len(collisions) > 0 {
+				return fmt.Errorf("users share a canonical email, merge or purge them first: %s", strings.Join(collisions, ", "))
+			}
+
+			for _, user := range users {
+				if err := tx.Table("users").Where("id = ?", user.ID).
+					UpdateColumn("email_index", indexes[user.ID]).Error; err != nil {
+					return err
+				}
+			}
+			log.Printf("Reindexed the emails of %d users", len(users))
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Case-only duplicates made after Up would break the old unique
+			// index, so the canonical indexes are kept
+			return nil
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// A phone number can be verified on only one account. Unverified numbers may
+// repeat, so the unique index on the phone blind index is partial. MySQL has
+// no partial indexes and indexes an expression that is NULL for unverified
+// numbers instead, since NULLs never collide. Numbers verified on more than
+// one account have to be resolved by hand first.
+
+type verifiedPhoneUser struct {
+	PhoneIndex string `gorm:"uniqueIndex:idx_users_verified_phone,where:is_phone_verified"`
+}
+
+func (verifiedPhoneUser) TableName() string { return "users" }
+
+const verifiedPhoneIndexName = "idx_users_verified_phone"
+
+func init() {
+	Register(Migration{
+		Version: 6,
+		Name:    "verified_phone_index",
+		Up: func(tx *gorm.DB) error {
+			var duplicates int64
+			if err := tx.Table("(?) AS d", tx.Table("users").Select("phone_index").
+				Where("is_phone_verified = ?", true).
+				Group("phone_index").Having("COUNT(*) > 1")).Count(&duplicates).Error; err != nil {
+				return err
+			}
+			if duplicates > 0 {
+				return fmt.Errorf("%d phone numbers are verified on more than one user, unverify them first", duplicates)
+			}
+
+			if tx.Dialector.Name() == "mysql" {
+				return tx.Exec("CREATE UNIQUE INDEX " + verifiedPhoneIndexName +
+					" ON users ((CASE WHEN is_phone_verified THEN phone_index END))").Error
+			}
+			return tx.Migrator().CreateIndex(&verifiedPhoneUser{}, verifiedPhoneIndexName)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&verifiedPhoneUser{}, verifiedPhoneIndexName)
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Deleting a default address promotes the most recently used remaining one,
+// which last_used_at records. Existing addresses were last used when they
+// were last updated.
+
+type lastUsedAddress struct {
+	LastUsedAt time.Time
+}
+
+func (lastUsedAddress) TableName() string { return "addresses" }
+
+func init() {
+	Register(Migration{
+		Version: 7,
+		Name:    "address_last_used",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&lastUsedAddress{}, "LastUsedAt") {
+				if err := tx.Migrator().AddColumn(&lastUsedAddress{}, "LastUsedAt"); err != nil {
+					return err
+				}
+			}
+			return tx.Exec("UPDATE addresses SET last_used_at = updated_at WHERE last_used_at IS NULL").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&lastUsedAddress{}, "LastUsedAt")
+		},
+	})
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/template"
+)
+
+var (
+	migrationNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_.*\.go$`)
+)
+
+var migrationTemplate = template.Must(template.New("migration").Parse(`package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	Register(Migration{
+		Version: {{.Version}},
+		Name:    "{{.Name}}",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`))
+
+// Create writes an empty migration named name into dir, numbered after the
+// highest version found there. It returns the path of the new file.
+func Create(dir, name string) (string, error) {
+	if !migrationNamePattern.MatchString(name) {
+		return "", fmt.Errorf("migration name must be snake_case, got %q", name)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	version := 0
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		if v, err := strconv.Atoi(match[1]); err == nil && v > version {
+			version = v
+		}
+	}
+	version++
+
+	path := filepath.Join(dir, fmt.Sprintf("%04d_%s.go", version, name))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data := struct {
+		Version int
+		Name    string
+	}{version, name}
+	if err := migrationTemplate.Execute(file, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
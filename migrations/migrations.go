@@ -0,0 +1,228 @@
+// Package migrations holds the versioned schema migrations of the service and
+// applies them under a lock, recording each applied version in the
+// schema_migrations table.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Migration is a numbered schema change. Up and Down run inside a
+// transaction where the database supports transactional DDL. Migrations must
+// not use the structs in the models package, which describe the latest schema
+// rather than the one the migration was written against.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Status is the state of a migration in a database.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+var ErrLocked = errors.New("migrations are locked by another process")
+
+const (
+	// lockTimeout is how long to wait for another migrator to finish.
+	lockTimeout      = 2 * time.Minute
+	lockPollInterval = time.Second
+	// staleLockAge is after how long a lock is considered left behind by a
+	// migrator that crashed and is taken over.
+	staleLockAge = time.Hour
+)
+
+// schemaMigration is a row of the schema_migrations table.
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// migrationLock is the single row held while migrations run.
+type migrationLock struct {
+	ID       int `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string
+	LockedAt time.Time
+}
+
+func (migrationLock) TableName() string { return "schema_migrations_lock" }
+
+var registry = map[int]Migration{}
+
+// Register adds a migration. It is called from the init function of each
+// migration file.
+func Register(m Migration) {
+	if _, dup := registry[m.Version]; dup {
+		panic(fmt.Sprintf("migrations: duplicate version %d", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// All returns every registered migration in version order.
+func All() []Migration {
+	all := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Up applies pending migrations in order, at most limit of them when limit is
+// positive. It returns the migrations it applied.
+func Up(db *gorm.DB, limit int) ([]Migration, error) {
+	var done []Migration
+	err := withLock(db, func() error {
+		pending, err := Pending(db)
+		if err != nil {
+			return err
+		}
+		if limit > 0 && len(pending) > limit {
+			pending = pending[:limit]
+		}
+
+		for _, m := range pending {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, newest first. It returns
+// the migrations it rolled back.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	var done []Migration
+	err := withLock(db, func() error {
+		var applied []schemaMigration
+		if err := db.Order("version DESC").Limit(steps).Find(&applied).Error; err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+
+		for _, row := range applied {
+			m, ok := registry[row.Version]
+			if !ok {
+				return fmt.Errorf("migration %d_%s is applied but unknown to this build", row.Version, row.Name)
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// StatusOf lists every registered migration with the time it was applied,
+// if it was.
+func StatusOf(db *gorm.DB) ([]Status, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range All() {
+		status := Status{Migration: m}
+		if row, ok := applied[m.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the registered migrations that have not been applied yet.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range All() {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func appliedVersions(db *gorm.DB) (map[int]schemaMigration, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// withLock runs fn while holding the migration lock, so replicas starting at
+// the same time do not apply migrations concurrently.
+func withLock(db *gorm.DB, fn func() error) error {
+	if err := db.AutoMigrate(&migrationLock{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations_lock: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		if err := db.Where("locked_at < ?", time.Now().Add(-staleLockAge)).Delete(&migrationLock{}).Error; err != nil {
+			return fmt.Errorf("failed to clear stale migration lock: %w", err)
+		}
+		// A conflict only means someone else holds the lock, so it is not logged
+		quiet := db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+		if err := quiet.Create(&migrationLock{ID: 1, Owner: owner, LockedAt: time.Now()}).Error; err == nil {
+			break
+		}
+
+		var holder migrationLock
+		if err := db.First(&holder, 1).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: held by %s since %s", ErrLocked, holder.Owner, holder.LockedAt.Format(time.RFC3339))
+		}
+		time.Sleep(lockPollInterval)
+	}
+	defer db.Where("id = ? AND owner = ?", 1, owner).Delete(&migrationLock{})
+
+	return fn()
+}
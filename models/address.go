@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Address usages a default can be set for
+const (
+	AddressUsageShipping = "shipping"
+	AddressUsageBilling  = "billing"
+)
+
+// Address is an entry in a user's address book.
+type Address struct {
+	ID                string `gorm:"primaryKey"`
+	UserID            string `gorm:"index"`
+	Label             string
+	RecipientName     string `gorm:"serializer:encrypted"`
+	Phone             string `gorm:"serializer:encrypted"`
+	StreetName        string `gorm:"serializer:encrypted"`
+	Locality          string
+	Landmark          string `gorm:"serializer:encrypted"`
+	State             string
+	Pincode           string
+	IsDefaultShipping bool
+	IsDefaultBilling  bool
+	// When the address was last added, edited or made a default
+	LastUsedAt time.Time `gorm:"autoCreateTime"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Audit log actions
+const (
+	AuditActionBan            = "user.ban"
+	AuditActionUnban          = "user.unban"
+	AuditActionDeleteAccount  = "user.delete_account"
+	AuditActionRestoreAccount = "user.restore_account"
+	AuditActionDataExport     = "user.data_export"
+)
+
+// AuditActorAdmin is recorded as the actor of admin-only RPCs.
+const AuditActorAdmin = "admin"
+
+// AuditActorUnknown is recorded as the actor when the caller is not
+// authenticated.
+const AuditActorUnknown = "unknown"
+
+// AuditLog records a security or compliance relevant action on an account.
+type AuditLog struct {
+	ID           uint `gorm:"primaryKey"`
+	ActorID      string
+	Action       string `gorm:"index"`
+	TargetUserID string `gorm:"index"`
+	Metadata     string
+	CreatedAt    time.Time
+}
+
+// LoginEvent records a login attempt against an existing account.
+type LoginEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"index"`
+	Success   bool
+	IPAddress string
+	UserAgent string
+	CreatedAt time.Time
+}
@@ -0,0 +1,117 @@
+package model
+
+import "time"
+
+// Badge tiers
+const (
+	BadgeTierBronze = "bronze"
+	BadgeTierSilver = "silver"
+	BadgeTierGold   = "gold"
+)
+
+// BadgeFacts is the snapshot of a user that badge rules are evaluated against.
+type BadgeFacts struct {
+	User *User
+	// ReasonCounts holds the number of ledger events per reason
+	ReasonCounts map[string]int64
+}
+
+// BadgeDefinition describes a badge and the rule that earns it.
+type BadgeDefinition struct {
+	ID          string
+	Name        string
+	Description string
+	Tier        string
+	Earned      func(facts BadgeFacts) bool
+}
+
+// UserBadge records a badge awarded to a user. A badge is awarded at most once.
+type UserBadge struct {
+	UserID    string `gorm:"primaryKey"`
+	BadgeID   string `gorm:"primaryKey"`
+	Tier      string `gorm:"index"`
+	AwardedAt time.Time
+}
+
+var (
+	badgeRegistry = map[string]BadgeDefinition{}
+	badgeOrder    []string
+)
+
+// RegisterBadge adds a badge to the registry, replacing any badge with the same ID.
+func RegisterBadge(def BadgeDefinition) {
+	if _, exists := badgeRegistry[def.ID]; !exists {
+		badgeOrder = append(badgeOrder, def.ID)
+	}
+	badgeRegistry[def.ID] = def
+}
+
+// Badges returns every registered badge in registration order.
+func Badges() []BadgeDefinition {
+	defs := make([]BadgeDefinition, 0, len(badgeOrder))
+	for _, id := range badgeOrder {
+		defs = append(defs, badgeRegistry[id])
+	}
+	return defs
+}
+
+// LookupBadge returns the registered badge with the given ID.
+func LookupBadge(id string) (BadgeDefinition, bool) {
+	def, ok := badgeRegistry[id]
+	return def, ok
+}
+
+func init() {
+	RegisterBadge(BadgeDefinition{
+		ID:          "verified_email",
+		Name:        "Verified email",
+		Description: "Verified the email address on the account",
+		Tier:        BadgeTierBronze,
+		Earned:      func(f BadgeFacts) bool { return f.User.IsVerified },
+	})
+	RegisterBadge(BadgeDefinition{
+		ID:          "autobiographer",
+		Name:        "Autobiographer",
+		Description: "Completed the address and phone number on the profile",
+		Tier:        BadgeTierBronze,
+		Earned: func(f BadgeFacts) bool {
+			u := f.User
+			return u.StreetName != "" && u.Locality != "" && u.State != "" && u.Pincode != "" && u.PhoneNumber != ""
+		},
+	})
+	RegisterBadge(BadgeDefinition{
+		ID:          "first_answer",
+		Name:        "First answer",
+		Description: "Posted a first answer",
+		Tier:        BadgeTierBronze,
+		Earned:      func(f BadgeFacts) bool { return f.ReasonCounts[ReputationReasonAnswer] >= 1 },
+	})
+	RegisterBadge(BadgeDefinition{
+		ID:          "upvotes_100",
+		Name:        "100 upvotes",
+		Description: "Received 100 upvotes",
+		Tier:        BadgeTierSilver,
+		Earned:      func(f BadgeFacts) bool { return f.ReasonCounts[ReputationReasonUpvote] >= 100 },
+	})
+	RegisterBadge(BadgeDefinition{
+		ID:          "established",
+		Name:        "Established",
+		Description: "Reached 1,000 reputation",
+		Tier:        BadgeTierSilver,
+		Earned:      func(f BadgeFacts) bool { return f.User.Reputation >= 1000 },
+	})
+	RegisterBadge(BadgeDefinition{
+		ID:          "upvotes_1000",
+		Name:        "1,000 upvotes",
+		Description: "Received 1,000 upvotes",
+		Tier:        BadgeTierGold,
+		Earned:      func(f BadgeFacts) bool { return f.ReasonCounts[ReputationReasonUpvote] >= 1000 },
+	})
+	RegisterBadge(BadgeDefinition{
+		ID:          "trusted",
+		Name:        "Trusted",
+		Description: "Reached 10,000 reputation",
+		Tier:        BadgeTierGold,
+		Earned:      func(f BadgeFacts) bool { return f.User.Reputation >= 10000 },
+	})
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Policy types a user can consent to
+const (
+	PolicyTerms     = "terms"
+	PolicyPrivacy   = "privacy"
+	PolicyMarketing = "marketing"
+)
+
+// Consent sources
+const (
+	ConsentSourceRegister = "register"
+	ConsentSourceAPI      = "api"
+)
+
+// Consent records that a user accepted a version of a policy. Rows are never
+// updated except to mark them withdrawn, so they form the consent history.
+type Consent struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      string `gorm:"index"`
+	PolicyType  string
+	Version     string
+	AcceptedAt  time.Time
+	WithdrawnAt *time.Time
+	SourceIP    string
+	Source      string
+}
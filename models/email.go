@@ -0,0 +1,64 @@
+package model
+
+import "strings"
+
+// EmailProviderRule lists the parts of an address a mail provider ignores
+// when delivering, so addresses differing only in them reach one mailbox.
+type EmailProviderRule struct {
+	IgnoreDots      bool   // dots in the local part, as Gmail does
+	StripSubaddress bool   // a "+tag" suffix of the local part
+	CanonicalDomain string // domain the provider's aliases map to
+}
+
+// DefaultEmailProviderRules is used when no provider rules are configured.
+var DefaultEmailProviderRules = map[string]EmailProviderRule{
+	"gmail.com":      {IgnoreDots: true, StripSubaddress: true},
+	"googlemail.com": {IgnoreDots: true, StripSubaddress: true, CanonicalDomain: "gmail.com"},
+}
+
+var emailProviderRules = DefaultEmailProviderRules
+
+// SetEmailProviderRules replaces the provider rules CanonicalEmail applies.
+func SetEmailProviderRules(rules map[string]EmailProviderRule) {
+	emailProviderRules = rules
+}
+
+// NormalizeEmail trims an email address and lowercases its domain, keeping
+// the local part as it was typed.
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	return email[:at] + strings.ToLower(email[at:])
+}
+
+// CanonicalEmail returns the form of an email address that identifies its
+// mailbox: trimmed, lowercased and with the rules of its provider applied.
+// Two addresses with the same canonical form belong to one account.
+func CanonicalEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+
+	rule, ok := emailProviderRules[domain]
+	if !ok {
+		return email
+	}
+	if rule.StripSubaddress {
+		if plus := strings.Index(local, "+"); plus > 0 {
+			local = local[:plus]
+		}
+	}
+	if rule.IgnoreDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	if rule.CanonicalDomain != "" {
+		domain = rule.CanonicalDomain
+	}
+	return local + "@" + domain
+}
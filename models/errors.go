@@ -1,13 +1,81 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
+
+// FieldError ties an error to the request field that caused it, so it can be
+// reported as a field violation.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string { return e.Field + ": " + e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// ValidationError lists every field of a request that breaks its validation
+// rules.
+type ValidationError struct {
+	Violations []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		msgs[i] = violation.Error()
+	}
+	return ErrInvalidRequest.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrInvalidRequest }
 
 var (
 	ErrUserNotFound    = errors.New("user not found")
-	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidPassword = errors.New("invalid email or password")
 	ErrTokenGeneration = errors.New("failed to generate token")
 	ErrInvalidToken    = errors.New("invalid token")
+	ErrMissingToken    = errors.New("bearer token is required")
 	ErrUserNotVerified = errors.New("user not verified")
 	ErrInvalidCode     = errors.New("invalid verification code")
 	ErrDuplicateEmail  = errors.New("email already exists")
+	ErrVersionConflict = errors.New("profile was modified by another request")
+	ErrAlreadyBanned   = errors.New("user is already banned")
+	ErrRequiredField   = errors.New("field is required")
+	ErrImmutableField  = errors.New("field cannot be updated")
+	ErrUnknownField    = errors.New("unknown field")
+	ErrInvalidRequest  = errors.New("invalid request")
+
+	ErrUnknownClient    = errors.New("client certificate is not mapped to a service identity")
+	ErrCallerNotAllowed = errors.New("caller is not allowed to call this method")
+
+	ErrTokenUserMismatch = errors.New("token does not belong to the requested user")
+
+	ErrInvalidLeaderboardPeriod = errors.New("invalid leaderboard period")
+	ErrInvalidPageToken         = errors.New("invalid page token")
+
+	ErrAddressNotFound     = errors.New("address not found")
+	ErrInvalidAddressUsage = errors.New("address usage must be shipping or billing")
+	ErrAddressLimitReached = errors.New("address book is full")
+
+	ErrInvalidPhoneNumber = errors.New("invalid phone number")
+	ErrNoPhoneNumber      = errors.New("no phone number to verify")
+	ErrDuplicatePhone     = errors.New("phone number is already verified on another account")
+	ErrCodeExpired        = errors.New("verification code expired")
+	ErrTooManyAttempts    = errors.New("too many verification attempts")
+
+	ErrInvalidEmail         = errors.New("invalid email address")
+	ErrEmailUnchanged       = errors.New("new email matches the current email")
+	ErrNoPendingEmailChange = errors.New("no pending email change")
+	ErrInvalidCancelToken   = errors.New("invalid cancel token")
+
+	ErrAccountNotDeleted    = errors.New("account is not scheduled for deletion")
+	ErrRestoreWindowExpired = errors.New("account can no longer be restored")
+
+	ErrTermsNotAccepted    = errors.New("the current terms of service must be accepted")
+	ErrUnknownPolicy       = errors.New("unknown policy type")
+	ErrStaleConsentVersion = errors.New("consent must be given to the current policy version")
+	ErrNoActiveConsent     = errors.New("no active consent to withdraw")
 )
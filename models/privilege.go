@@ -0,0 +1,48 @@
+package model
+
+import "sort"
+
+// Privilege names unlocked as a user's reputation grows.
+const (
+	PrivilegeUpvote   = "upvote"
+	PrivilegeFlag     = "flag"
+	PrivilegeComment  = "comment"
+	PrivilegeEdit     = "edit"
+	PrivilegeModerate = "moderate"
+)
+
+// PrivilegeTier maps a reputation threshold to the privilege it unlocks.
+type PrivilegeTier struct {
+	Name          string
+	MinReputation int32
+}
+
+// DefaultPrivilegeTiers is used when no privilege table is configured.
+var DefaultPrivilegeTiers = []PrivilegeTier{
+	{Name: PrivilegeUpvote, MinReputation: 15},
+	{Name: PrivilegeFlag, MinReputation: 15},
+	{Name: PrivilegeComment, MinReputation: 50},
+	{Name: PrivilegeEdit, MinReputation: 2000},
+	{Name: PrivilegeModerate, MinReputation: 10000},
+}
+
+// PrivilegesFor returns the privileges unlocked at the given reputation,
+// ordered by threshold and then by name.
+func PrivilegesFor(tiers []PrivilegeTier, reputation int32) []string {
+	sorted := make([]PrivilegeTier, len(tiers))
+	copy(sorted, tiers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].MinReputation != sorted[j].MinReputation {
+			return sorted[i].MinReputation < sorted[j].MinReputation
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	privileges := []string{}
+	for _, tier := range sorted {
+		if reputation >= tier.MinReputation {
+			privileges = append(privileges, tier.Name)
+		}
+	}
+	return privileges
+}
@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// ReputationEvent is a single entry in the reputation ledger. The user's
+// Reputation is the running total of their events.
+type ReputationEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"index"`
+	Delta     int32
+	Reason    string
+	SourceID  string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// Well-known ledger reasons reported by the Content Service
+const (
+	ReputationReasonUpvote = "upvote"
+	ReputationReasonAnswer = "answer"
+)
+
+// Leaderboard periods
+const (
+	LeaderboardAllTime = "all_time"
+	LeaderboardWeek    = "week"
+	LeaderboardMonth   = "month"
+	LeaderboardYear    = "year"
+)
+
+// LeaderboardPeriods lists every period the aggregates are refreshed for.
+var LeaderboardPeriods = []string{LeaderboardAllTime, LeaderboardWeek, LeaderboardMonth, LeaderboardYear}
+
+// LeaderboardEntry is a precomputed aggregate of the reputation a user gained
+// in a period. Rows are rebuilt by the leaderboard refresher, which copies the
+// user's encrypted name as is.
+type LeaderboardEntry struct {
+	Period      string `gorm:"primaryKey"`
+	UserID      string `gorm:"primaryKey"`
+	Name        string `gorm:"serializer:encrypted"`
+	State       string `gorm:"index"`
+	Locality    string `gorm:"index"`
+	Points      int64  `gorm:"index"`
+	RefreshedAt time.Time
+}
+
+// LeaderboardFilter selects a page of a leaderboard.
+type LeaderboardFilter struct {
+	Period   string
+	State    string
+	Locality string
+	Offset   int
+	Limit    int
+}
+
+// LeaderboardPeriodStart returns the UTC instant a period begins at, relative
+// to now. Weeks start on Monday. The all-time period has a zero start.
+func LeaderboardPeriodStart(period string, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case LeaderboardAllTime:
+		return time.Time{}, nil
+	case LeaderboardWeek:
+		daysSinceMonday := (int(today.Weekday()) + 6) % 7
+		return today.AddDate(0, 0, -daysSinceMonday), nil
+	case LeaderboardMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case LeaderboardYear:
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, ErrInvalidLeaderboardPeriod
+	}
+}
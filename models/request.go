@@ -1 +1 @@
-package model
\ No newline at end of file
+package model
@@ -1,17 +1,79 @@
 package model
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/liju-github/EcommerceUserService/encryption"
+)
+
+// User PII is encrypted at rest. Email and PhoneNumber are looked up through
+// their blind indexes, which must be refreshed with RefreshBlindIndexes
+// whenever either changes.
 type User struct {
-	ID             string
-	Email          string
-	PasswordHash   string
-	Name           string
-	StreetName     string
-	Locality       string
-	State          string
-	Pincode        string
-	PhoneNumber    string
-	Reputation     int32
+	ID               string `gorm:"primaryKey"`
+	Email            string `gorm:"serializer:encrypted"`
+	EmailIndex       string `gorm:"uniqueIndex"`
+	PasswordHash     string
+	Name             string `gorm:"serializer:encrypted"`
+	StreetName       string `gorm:"serializer:encrypted"`
+	Locality         string
+	State            string
+	Pincode          string
+	PhoneNumber      string `gorm:"serializer:encrypted"`
+	PhoneIndex       string `gorm:"index"`
+	Reputation       int32
 	VerificationCode string
-	IsBanned bool
-	IsVerified     bool
+	IsBanned         bool
+	IsVerified       bool
+
+	// Version is bumped by every profile write and guards against lost
+	// updates from clients editing a stale copy
+	Version int64 `gorm:"not null;default:1"`
+
+	// Phone verification. PendingPhoneNumber holds the number an OTP was
+	// sent to until it is confirmed.
+	IsPhoneVerified    bool
+	PendingPhoneNumber string `gorm:"serializer:encrypted"`
+	PhoneOTPHash       string
+	PhoneOTPExpiresAt  time.Time
+	PhoneOTPAttempts   int
+
+	// Email change. The new address only replaces Email once the code sent
+	// to it is confirmed; the cancel token is sent to the old address.
+	PendingEmail          string `gorm:"serializer:encrypted"`
+	EmailChangeCodeHash   string
+	EmailChangeCancelHash string
+	EmailChangeExpiresAt  time.Time
+	EmailChangeAttempts   int
+
+	// Account deletion. A deleted account can be restored until PurgeAfter,
+	// after which its PII is anonymized and PurgedAt is set.
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	PurgeAfter *time.Time
+	PurgedAt   *time.Time
+}
+
+// RefreshBlindIndexes recomputes the lookup indexes of Email and PhoneNumber.
+func (u *User) RefreshBlindIndexes() error {
+	emailIndex, err := EmailIndex(u.Email)
+	if err != nil {
+		return err
+	}
+	phoneIndex, err := encryption.BlindIndex(u.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	u.EmailIndex = emailIndex
+	u.PhoneIndex = phoneIndex
+	return nil
+}
+
+// EmailIndex returns the blind index of an email address, computed over its
+// canonical form so every spelling of an address finds the same user.
+func EmailIndex(email string) (string, error) {
+	return encryption.BlindIndex(CanonicalEmail(email))
 }
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Mailer delivers plain text emails.
+type Mailer interface {
+	SendEmail(ctx context.Context, to, subject, body string) error
+}
+
+// NewMailer returns a mailer that appends messages to outboxPath, or one
+// that logs them when no path is configured.
+func NewMailer(outboxPath string) Mailer {
+	if outboxPath == "" {
+		return LogMailer{}
+	}
+	return &FileMailer{Path: outboxPath}
+}
+
+// LogMailer writes emails to the service log instead of sending them.
+type LogMailer struct{}
+
+func (LogMailer) SendEmail(ctx context.Context, to, subject, body string) error {
+	log.Printf("Email to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// FileMailer appends emails to a file. It stands in for a real mail
+// provider in development and tests.
+type FileMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (f *FileMailer) SendEmail(ctx context.Context, to, subject, body string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail outbox: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC1123Z), to, subject, body); err != nil {
+		return fmt.Errorf("failed to write mail outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// SMSSender delivers text messages to E.164 phone numbers.
+type SMSSender interface {
+	SendSMS(ctx context.Context, to, message string) error
+}
+
+// NewSMSSender returns a sender that appends messages to outboxPath, or one
+// that logs them when no path is configured.
+func NewSMSSender(outboxPath string) SMSSender {
+	if outboxPath == "" {
+		return LogSMSSender{}
+	}
+	return &FileSMSSender{Path: outboxPath}
+}
+
+// LogSMSSender writes messages to the service log instead of sending them.
+type LogSMSSender struct{}
+
+func (LogSMSSender) SendSMS(ctx context.Context, to, message string) error {
+	log.Printf("SMS to %s: %s", to, message)
+	return nil
+}
+
+// FileSMSSender appends messages to a file, one line per message. It stands
+// in for a real SMS gateway in development and tests.
+type FileSMSSender struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (f *FileSMSSender) SendSMS(ctx context.Context, to, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open SMS outbox: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), to, message); err != nil {
+		return fmt.Errorf("failed to write SMS outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package postal
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidPincode  = errors.New("pincode must be 6 digits and cannot start with 0")
+	ErrUnknownState    = errors.New("unknown state")
+	ErrPincodeMismatch = errors.New("pincode does not belong to the given state")
+	ErrPincodeNotFound = errors.New("pincode not found")
+)
+
+// PincodeInfo describes the district and state a pincode is delivered in.
+type PincodeInfo struct {
+	Pincode   string
+	District  string
+	StateCode string
+	StateName string
+}
+
+//go:embed data/pincodes.csv
+var bundledDataset []byte
+
+var (
+	pincodeFormat = regexp.MustCompile(`^[1-9][0-9]{5}$`)
+
+	mu       sync.RWMutex
+	pincodes map[string]PincodeInfo
+
+	statesByKey  = map[string]State{}
+	statesByCode = map[string]State{}
+)
+
+func init() {
+	for _, state := range States {
+		statesByCode[state.Code] = state
+		statesByKey[normalizeKey(state.Code)] = state
+		statesByKey[normalizeKey(state.Name)] = state
+	}
+	for alias, code := range stateAliases {
+		statesByKey[normalizeKey(alias)] = statesByCode[code]
+	}
+
+	dataset, err := parseDataset(bytes.NewReader(bundledDataset))
+	if err != nil {
+		panic(fmt.Sprintf("postal: bundled pincode dataset is invalid: %v", err))
+	}
+	pincodes = dataset
+}
+
+// LoadDataset replaces the bundled pincode dataset with the CSV file at path.
+// The file has a header row followed by pincode,district,state rows, where
+// state is a code or name NormalizeState accepts.
+func LoadDataset(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open pincode dataset: %w", err)
+	}
+	defer f.Close()
+
+	dataset, err := parseDataset(f)
+	if err != nil {
+		return fmt.Errorf("failed to load pincode dataset %s: %w", path, err)
+	}
+
+	mu.Lock()
+	pincodes = dataset
+	mu.Unlock()
+	return nil
+}
+
+func parseDataset(r io.Reader) (map[string]PincodeInfo, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("missing header: %w", err)
+	}
+
+	dataset := map[string]PincodeInfo{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pincode := strings.TrimSpace(record[0])
+		if !pincodeFormat.MatchString(pincode) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPincode, pincode)
+		}
+		state, err := lookupState(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("pincode %s: %w", pincode, err)
+		}
+		dataset[pincode] = PincodeInfo{
+			Pincode:   pincode,
+			District:  strings.TrimSpace(record[1]),
+			StateCode: state.Code,
+			StateName: state.Name,
+		}
+	}
+	return dataset, nil
+}
+
+// NormalizeState returns the canonical code for a state code, name or
+// common alias, ignoring case and extra whitespace.
+func NormalizeState(state string) (string, error) {
+	s, err := lookupState(state)
+	if err != nil {
+		return "", err
+	}
+	return s.Code, nil
+}
+
+// StateName returns the display name for a canonical state code.
+func StateName(code string) string {
+	return statesByCode[code].Name
+}
+
+func lookupState(state string) (State, error) {
+	s, ok := statesByKey[normalizeKey(state)]
+	if !ok {
+		return State{}, fmt.Errorf("%w: %q", ErrUnknownState, state)
+	}
+	return s, nil
+}
+
+// Lookup returns the district and state for a pincode. Pincodes missing from
+// the dataset still resolve to a state when their prefix belongs to exactly
+// one postal circle, without a district.
+func Lookup(pincode string) (PincodeInfo, error) {
+	pincode = strings.TrimSpace(pincode)
+	if !pincodeFormat.MatchString(pincode) {
+		return PincodeInfo{}, ErrInvalidPincode
+	}
+
+	mu.RLock()
+	info, ok := pincodes[pincode]
+	mu.RUnlock()
+	if ok {
+		return info, nil
+	}
+
+	candidates := prefixCandidates(pincode)
+	if len(candidates) != 1 {
+		return PincodeInfo{}, ErrPincodeNotFound
+	}
+	return PincodeInfo{
+		Pincode:   pincode,
+		StateCode: candidates[0],
+		StateName: StateName(candidates[0]),
+	}, nil
+}
+
+// Validate checks the pincode format and that the pincode is delivered in
+// the given state. It returns the canonical state code. When state is empty
+// it is derived from the pincode if that is unambiguous.
+func Validate(pincode, state string) (string, error) {
+	pincode = strings.TrimSpace(pincode)
+	if !pincodeFormat.MatchString(pincode) {
+		return "", ErrInvalidPincode
+	}
+
+	if strings.TrimSpace(state) == "" {
+		info, err := Lookup(pincode)
+		if err != nil {
+			return "", err
+		}
+		return info.StateCode, nil
+	}
+
+	code, err := NormalizeState(state)
+	if err != nil {
+		return "", err
+	}
+
+	mu.RLock()
+	info, ok := pincodes[pincode]
+	mu.RUnlock()
+	if ok {
+		if info.StateCode != code {
+			return "", ErrPincodeMismatch
+		}
+		return code, nil
+	}
+
+	for _, candidate := range prefixCandidates(pincode) {
+		if candidate == code {
+			return code, nil
+		}
+	}
+	return "", ErrPincodeMismatch
+}
+
+// prefixCandidates returns the states a pincode's prefix may belong to.
+func prefixCandidates(pincode string) []string {
+	if states, ok := prefixStates[pincode[:3]]; ok {
+		return states
+	}
+	return prefixStates[pincode[:2]]
+}
+
+func normalizeKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,105 @@
+package postal
+
+// State is an Indian state or union territory identified by its
+// ISO 3166-2:IN subdivision code.
+type State struct {
+	Code string
+	Name string
+}
+
+// States lists the canonical states and union territories.
+var States = []State{
+	{"AN", "Andaman and Nicobar Islands"},
+	{"AP", "Andhra Pradesh"},
+	{"AR", "Arunachal Pradesh"},
+	{"AS", "Assam"},
+	{"BR", "Bihar"},
+	{"CH", "Chandigarh"},
+	{"CG", "Chhattisgarh"},
+	{"DH", "Dadra and Nagar Haveli and Daman and Diu"},
+	{"DL", "Delhi"},
+	{"GA", "Goa"},
+	{"GJ", "Gujarat"},
+	{"HR", "Haryana"},
+	{"HP", "Himachal Pradesh"},
+	{"JK", "Jammu and Kashmir"},
+	{"JH", "Jharkhand"},
+	{"KA", "Karnataka"},
+	{"KL", "Kerala"},
+	{"LA", "Ladakh"},
+	{"LD", "Lakshadweep"},
+	{"MP", "Madhya Pradesh"},
+	{"MH", "Maharashtra"},
+	{"MN", "Manipur"},
+	{"ML", "Meghalaya"},
+	{"MZ", "Mizoram"},
+	{"NL", "Nagaland"},
+	{"OD", "Odisha"},
+	{"PY", "Puducherry"},
+	{"PB", "Punjab"},
+	{"RJ", "Rajasthan"},
+	{"SK", "Sikkim"},
+	{"TN", "Tamil Nadu"},
+	{"TG", "Telangana"},
+	{"TR", "Tripura"},
+	{"UP", "Uttar Pradesh"},
+	{"UK", "Uttarakhand"},
+	{"WB", "West Bengal"},
+}
+
+// stateAliases maps former names, former codes and common spellings to the
+// canonical code. Keys are normalized with normalizeKey.
+var stateAliases = map[string]string{
+	"orissa":                    "OD",
+	"or":                        "OD",
+	"pondicherry":               "PY",
+	"uttaranchal":               "UK",
+	"ut":                        "UK",
+	"ct":                        "CG",
+	"ts":                        "TG",
+	"nct of delhi":              "DL",
+	"new delhi":                 "DL",
+	"j&k":                       "JK",
+	"jammu & kashmir":           "JK",
+	"andaman & nicobar":         "AN",
+	"andaman & nicobar islands": "AN",
+	"andaman and nicobar":       "AN",
+	"dadra and nagar haveli":    "DH",
+	"daman and diu":             "DH",
+	"dn":                        "DH",
+	"dd":                        "DH",
+}
+
+// prefixStates maps the leading digits of a pincode to the states whose
+// postal circles use them. Three digit prefixes take precedence over two
+// digit ones, and some prefixes are shared by neighbouring states.
+var prefixStates = map[string][]string{
+	"11": {"DL"},
+	"12": {"HR"}, "13": {"HR"},
+	"14": {"PB"}, "15": {"PB"}, "16": {"PB"}, "160": {"CH", "PB", "HR"},
+	"17": {"HP"},
+	"18": {"JK"}, "19": {"JK"}, "194": {"LA", "JK"},
+	"20": {"UP"}, "21": {"UP"}, "22": {"UP"}, "23": {"UP"}, "24": {"UP"}, "25": {"UP"},
+	"26": {"UP"}, "27": {"UP"}, "28": {"UP"},
+	"244": {"UP", "UK"}, "246": {"UK"}, "247": {"UP", "UK"}, "248": {"UK"}, "249": {"UK"},
+	"262": {"UP", "UK"}, "263": {"UK"},
+	"30": {"RJ"}, "31": {"RJ"}, "32": {"RJ"}, "33": {"RJ"}, "34": {"RJ"},
+	"36": {"GJ"}, "37": {"GJ"}, "38": {"GJ"}, "39": {"GJ"}, "362": {"GJ", "DH"}, "396": {"GJ", "DH"},
+	"40": {"MH"}, "41": {"MH"}, "42": {"MH"}, "43": {"MH"}, "44": {"MH"}, "403": {"GA"},
+	"45": {"MP"}, "46": {"MP"}, "47": {"MP"}, "48": {"MP"},
+	"49": {"CG"},
+	"50": {"TG", "AP"},
+	"51": {"AP", "TG"}, "52": {"AP"}, "53": {"AP"}, "533": {"AP", "PY"},
+	"56": {"KA"}, "57": {"KA"}, "58": {"KA"}, "59": {"KA"},
+	"60": {"TN"}, "61": {"TN"}, "62": {"TN"}, "63": {"TN"}, "64": {"TN"},
+	"605": {"PY", "TN"}, "607": {"TN", "PY"}, "609": {"TN", "PY"},
+	"67": {"KL"}, "673": {"KL", "PY"}, "68": {"KL"}, "69": {"KL"}, "682": {"KL", "LD"},
+	"70": {"WB"}, "71": {"WB"}, "72": {"WB"}, "73": {"WB"}, "74": {"WB"},
+	"737": {"SK", "WB"}, "744": {"AN"},
+	"75": {"OD"}, "76": {"OD"}, "77": {"OD"},
+	"78":  {"AS"},
+	"790": {"AR"}, "791": {"AR"}, "792": {"AR", "AS"},
+	"793": {"ML"}, "794": {"ML"}, "795": {"MN"}, "796": {"MZ"},
+	"797": {"NL"}, "798": {"NL"}, "799": {"TR"},
+	"80": {"BR"}, "81": {"BR", "JH"}, "82": {"BR", "JH"}, "83": {"JH"}, "84": {"BR"}, "85": {"BR"},
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"gorm.io/gorm"
+)
+
+// SoftDeleteUser marks the account as deleted and schedules its purge
+func (r *userRepository) SoftDeleteUser(ctx context.Context, userID string, purgeAfter time.Time) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).Where("id = ?", userID).Update("purge_after", purgeAfter)
+		if result.Error != nil {
+			return fmt.Errorf("failed to schedule account purge: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrUserNotFound
+		}
+
+		if err := tx.Where("id = ?", userID).Delete(&model.User{}).Error; err != nil {
+			return fmt.Errorf("failed to delete account: %w", err)
+		}
+		return nil
+	})
+}
+
+// RestoreUser undoes a soft delete while the grace period is still running
+func (r *userRepository) RestoreUser(ctx context.Context, userID string, now time.Time) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var user model.User
+	if err := db.Unscoped().Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrUserNotFound
+		}
+		return fmt.Errorf("failed to find account: %w", err)
+	}
+
+	if !user.DeletedAt.Valid {
+		return model.ErrAccountNotDeleted
+	}
+	if user.PurgedAt != nil || (user.PurgeAfter != nil && !now.Before(*user.PurgeAfter)) {
+		return model.ErrRestoreWindowExpired
+	}
+
+	if err := db.Unscoped().Model(&model.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"deleted_at": nil, "purge_after": nil}).Error; err != nil {
+		return fmt.Errorf("failed to restore account: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeletedUsers anonymizes the PII of every deleted account whose grace
+// period has ended and removes its address book and login history. The rows themselves are
+// kept so the reputation ledger stays consistent. The query timeout applies
+// to each account. It returns how many accounts were purged.
+func (r *userRepository) PurgeDeletedUsers(ctx context.Context, now time.Time) (int, error) {
+	userIDs, err := r.findUsersToPurge(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, userID := range userIDs {
+		if err := r.purgeUser(ctx, userID, now); err != nil {
+			return i, fmt.Errorf("failed to purge account %s: %w", userID, err)
+		}
+	}
+	return len(userIDs), nil
+}
+
+func (r *userRepository) findUsersToPurge(ctx context.Context, now time.Time) ([]string, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var userIDs []string
+	if err := db.Unscoped().Model(&model.User{}).
+		Where("deleted_at IS NOT NULL AND purged_at IS NULL AND purge_after <= ?", now).
+		Pluck("id", &userIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find accounts to purge: %w", err)
+	}
+	return userIDs, nil
+}
+
+func (r *userRepository) purgeUser(ctx context.Context, userID string, now time.Time) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	purged := model.User{
+		ID:       userID,
+		Email:    fmt.Sprintf("deleted-%s@invalid", userID),
+		Name:     "Deleted user",
+		PurgedAt: &now,
+	}
+	if err := purged.RefreshBlindIndexes(); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&model.User{}).Where("id = ?", userID).
+			Select("email", "email_index", "password_hash", "name", "street_name", "locality", "state",
+				"pincode", "phone_number", "phone_index", "is_phone_verified", "verification_code",
+				"pending_phone_number", "phone_otp_hash", "pending_email", "email_change_code_hash",
+				"email_change_cancel_hash", "purged_at").
+			Updates(&purged).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.LoginEvent{}).Error; err != nil {
+			return err
+		}
+		// Consents are kept as evidence of what was agreed to, without the source IP
+		if err := tx.Model(&model.Consent{}).Where("user_id = ?", userID).Update("source_ip", "").Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.Address{}).Error
+	})
+}
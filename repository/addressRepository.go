@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"gorm.io/gorm"
+)
+
+// CreateAddress adds an address to a user's address book
+func (r *userRepository) CreateAddress(ctx context.Context, address *model.Address) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if err := db.Create(address).Error; err != nil {
+		return fmt.Errorf("failed to create address: %w", err)
+	}
+	return nil
+}
+
+// GetAddress retrieves one of the user's addresses
+func (r *userRepository) GetAddress(ctx context.Context, userID, addressID string) (*model.Address, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var address model.Address
+	if err := db.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.ErrAddressNotFound
+		}
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+	return &address, nil
+}
+
+// ListAddresses retrieves the user's address book, oldest first
+func (r *userRepository) ListAddresses(ctx context.Context, userID string) ([]*model.Address, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var addresses []*model.Address
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&addresses).Error; err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	return addresses, nil
+}
+
+// CountAddresses returns the number of addresses in the user's address book
+func (r *userRepository) CountAddresses(ctx context.Context, userID string) (int64, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var count int64
+	if err := db.Model(&model.Address{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count addresses: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateAddress updates the editable fields of an address
+func (r *userRepository) UpdateAddress(ctx context.Context, address *model.Address) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	address.LastUsedAt = time.Now()
+	result := db.Model(&model.Address{}).Where("id = ? AND user_id = ?", address.ID, address.UserID).
+		Select("label", "recipient_name", "phone", "street_name", "locality", "landmark", "state", "pincode", "last_used_at").
+		Updates(address)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update address: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return model.ErrAddressNotFound
+	}
+	return nil
+}
+
+// DeleteAddress removes an address from the user's address book. When it was
+// a default, the most recently used remaining address takes its place.
+func (r *userRepository) DeleteAddress(ctx context.Context, userID, addressID string) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var address model.Address
+		if err := tx.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return model.ErrAddressNotFound
+			}
+			return fmt.Errorf("failed to get address: %w", err)
+		}
+
+		result := tx.Where("id = ? AND user_id = ?", addressID, userID).Delete(&model.Address{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete address: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrAddressNotFound
+		}
+
+		columns := map[string]interface{}{}
+		if address.IsDefaultShipping {
+			columns["is_default_shipping"] = true
+		}
+		if address.IsDefaultBilling {
+			columns["is_default_billing"] = true
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+
+		var successor model.Address
+		err := tx.Where("user_id = ?", userID).Order("last_used_at DESC").Order("created_at DESC").First(&successor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find the next default address: %w", err)
+		}
+		if err := tx.Model(&model.Address{}).Where("id = ?", successor.ID).Updates(columns).Error; err != nil {
+			return fmt.Errorf("failed to promote default address: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetDefaultAddress makes an address the user's default for the given usage,
+// clearing the flag on every other address
+func (r *userRepository) SetDefaultAddress(ctx context.Context, userID, addressID, usage string) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var column string
+	switch usage {
+	case model.AddressUsageShipping:
+		column = "is_default_shipping"
+	case model.AddressUsageBilling:
+		column = "is_default_billing"
+	default:
+		return model.ErrInvalidAddressUsage
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Address{}).Where("id = ? AND user_id = ?", addressID, userID).
+			Updates(map[string]interface{}{column: true, "last_used_at": time.Now()})
+		if result.Error != nil {
+			return fmt.Errorf("failed to set default address: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrAddressNotFound
+		}
+
+		// Losing the flag is not a use of the other addresses, so their
+		// timestamps are left alone
+		if err := tx.Model(&model.Address{}).Where("user_id = ? AND id <> ?", userID, addressID).
+			UpdateColumn(column, false).Error; err != nil {
+			return fmt.Errorf("failed to clear previous default address: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+func TestDeleteAddressPromotesMostRecentlyUsed(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		ctx := context.Background()
+
+		for _, id := range []string{"addr_a", "addr_b", "addr_c"} {
+			if err := repo.CreateAddress(ctx, &model.Address{ID: id, UserID: "usr_a", Label: id}); err != nil {
+				t.Fatalf("CreateAddress(%s) = %v", id, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// b is used after c was added, then a is made the default, which must
+		// not count as a use of the others
+		if err := repo.UpdateAddress(ctx, &model.Address{ID: "addr_b", UserID: "usr_a", Label: "Work"}); err != nil {
+			t.Fatalf("UpdateAddress() = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := repo.SetDefaultAddress(ctx, "usr_a", "addr_a", model.AddressUsageShipping); err != nil {
+			t.Fatalf("SetDefaultAddress() = %v", err)
+		}
+
+		if err := repo.DeleteAddress(ctx, "usr_a", "addr_a"); err != nil {
+			t.Fatalf("DeleteAddress() = %v", err)
+		}
+		addresses, err := repo.ListAddresses(ctx, "usr_a")
+		if err != nil {
+			t.Fatalf("ListAddresses() = %v", err)
+		}
+		for _, address := range addresses {
+			if want := address.ID == "addr_b"; address.IsDefaultShipping != want {
+				t.Errorf("%s default shipping = %v, want %v", address.ID, address.IsDefaultShipping, want)
+			}
+			if address.IsDefaultBilling {
+				t.Errorf("%s became the default billing address", address.ID)
+			}
+		}
+	})
+}
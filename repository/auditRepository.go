@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+// CreateAuditLog appends an entry to the audit log
+func (r *userRepository) CreateAuditLog(ctx context.Context, entry *model.AuditLog) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if err := db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLogs retrieves the audit entries targeting a user, optionally
+// limited to the given actions, oldest first
+func (r *userRepository) GetAuditLogs(ctx context.Context, userID string, actions ...string) ([]*model.AuditLog, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	query := db.Where("target_user_id = ?", userID)
+	if len(actions) > 0 {
+		query = query.Where("action IN ?", actions)
+	}
+
+	var entries []*model.AuditLog
+	if err := query.Order("created_at").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get audit logs: %w", err)
+	}
+	return entries, nil
+}
+
+// CreateLoginEvent records a login attempt
+func (r *userRepository) CreateLoginEvent(ctx context.Context, event *model.LoginEvent) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if err := db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	return nil
+}
+
+// GetLoginEvents retrieves the user's login history, oldest first
+func (r *userRepository) GetLoginEvents(ctx context.Context, userID string) ([]*model.LoginEvent, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var events []*model.LoginEvent
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get login history: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"gorm.io/gorm/clause"
+)
+
+// AwardBadges stores badge awards, ignoring badges the user already holds
+func (r *userRepository) AwardBadges(ctx context.Context, badges []*model.UserBadge) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if len(badges) == 0 {
+		return nil
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&badges).Error; err != nil {
+		return fmt.Errorf("failed to award badges: %w", err)
+	}
+	return nil
+}
+
+// GetUserBadges retrieves the badges awarded to a user, oldest first
+func (r *userRepository) GetUserBadges(ctx context.Context, userID string) ([]*model.UserBadge, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var badges []*model.UserBadge
+	if err := db.Where("user_id = ?", userID).Order("awarded_at").Find(&badges).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user badges: %w", err)
+	}
+	return badges, nil
+}
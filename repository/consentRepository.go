@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"gorm.io/gorm"
+)
+
+// CreateConsent records a policy acceptance
+func (r *userRepository) CreateConsent(ctx context.Context, consent *model.Consent) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if err := db.Create(consent).Error; err != nil {
+		return fmt.Errorf("failed to record consent: %w", err)
+	}
+	return nil
+}
+
+// GetConsents retrieves the user's full consent history, newest first
+func (r *userRepository) GetConsents(ctx context.Context, userID string) ([]*model.Consent, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var consents []*model.Consent
+	if err := db.Where("user_id = ?", userID).Order("accepted_at DESC").Order("id DESC").Find(&consents).Error; err != nil {
+		return nil, fmt.Errorf("failed to get consents: %w", err)
+	}
+	return consents, nil
+}
+
+// GetActiveConsent retrieves the newest consent for a policy that has not
+// been withdrawn, or nil if there is none
+func (r *userRepository) GetActiveConsent(ctx context.Context, userID, policyType string) (*model.Consent, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var consent model.Consent
+	if err := db.Where("user_id = ? AND policy_type = ? AND withdrawn_at IS NULL", userID, policyType).
+		Order("accepted_at DESC").Order("id DESC").First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get consent: %w", err)
+	}
+	return &consent, nil
+}
+
+// WithdrawConsents marks every active consent for a policy as withdrawn
+func (r *userRepository) WithdrawConsents(ctx context.Context, userID, policyType string, at time.Time) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	result := db.Model(&model.Consent{}).
+		Where("user_id = ? AND policy_type = ? AND withdrawn_at IS NULL", userID, policyType).
+		Update("withdrawn_at", at)
+	if result.Error != nil {
+		return fmt.Errorf("failed to withdraw consent: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return model.ErrNoActiveConsent
+	}
+	return nil
+}
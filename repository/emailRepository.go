@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+// UpdateEmailChange stores the email address and pending email change state of a user
+func (r *userRepository) UpdateEmailChange(ctx context.Context, user *model.User) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if err := user.RefreshBlindIndexes(); err != nil {
+		return err
+	}
+	err := updateVersioned(db, user, "update email change", "email", "email_index", "pending_email", "email_change_code_hash",
+		"email_change_cancel_hash", "email_change_expires_at", "email_change_attempts")
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return model.ErrDuplicateEmail
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+// Rekey re-encrypts the PII of every user, deleted ones included, and every
+// address under the primary key, refreshing the users' blind indexes on the
+// way. Each row is read and rewritten in its own transaction with the row
+// locked, so writes made while rekey runs are neither lost nor overwritten.
+// The query timeout applies to each query. It returns how many rows were
+// rewritten.
+func (r *userRepository) Rekey(ctx context.Context, batchSize int) (int, error) {
+	rewritten := 0
+	tables := []struct {
+		model interface{}
+		rekey func(ctx context.Context, tx *userRepository, id string) (bool, error)
+	}{
+		{&model.User{}, rekeyUser},
+		{&model.Address{}, rekeyAddress},
+	}
+	for _, table := range tables {
+		lastID := ""
+		for {
+			ids, err := r.nextIDs(ctx, table.model, lastID, batchSize)
+			if err != nil {
+				return rewritten, err
+			}
+			if len(ids) == 0 {
+				break
+			}
+			for _, id := range ids {
+				var found bool
+				err := r.WithinTx(ctx, func(tx UserRepository) error {
+					var err error
+					found, err = table.rekey(ctx, tx.(*userRepository), id)
+					return err
+				})
+				if err != nil {
+					return rewritten, err
+				}
+				if found {
+					rewritten++
+				}
+			}
+			lastID = ids[len(ids)-1]
+		}
+	}
+	return rewritten, nil
+}
+
+// nextIDs returns the IDs of the batch of rows following afterID, deleted
+// users included
+func (r *userRepository) nextIDs(ctx context.Context, table interface{}, afterID string, batchSize int) ([]string, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var ids []string
+	if err := db.Unscoped().Model(table).Where("id > ?", afterID).Order("id").Limit(batchSize).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to list rows to re-encrypt: %w", err)
+	}
+	return ids, nil
+}
+
+// rekeyUser rewrites a user, reporting false when it no longer exists
+func rekeyUser(ctx context.Context, tx *userRepository, id string) (bool, error) {
+	db, cancel := tx.withContext(ctx)
+	defer cancel()
+
+	var user model.User
+	err := db.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load user %s: %w", id, err)
+	}
+	if err := user.RefreshBlindIndexes(); err != nil {
+		return false, err
+	}
+	if err := db.Unscoped().Model(&model.User{}).Where("id = ?", user.ID).
+		Select("email", "email_index", "name", "street_name", "phone_number", "phone_index",
+			"pending_phone_number", "pending_email").
+		UpdateColumns(&user).Error; err != nil {
+		return false, fmt.Errorf("failed to re-encrypt user %s: %w", user.ID, err)
+	}
+	return true, nil
+}
+
+// rekeyAddress rewrites an address, reporting false when it was deleted
+// since it was listed
+func rekeyAddress(ctx context.Context, tx *userRepository, id string) (bool, error) {
+	db, cancel := tx.withContext(ctx)
+	defer cancel()
+
+	var address model.Address
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load address %s: %w", id, err)
+	}
+	if err := db.Model(&model.Address{}).Where("id = ?", address.ID).
+		Select("recipient_name", "phone", "street_name", "landmark").
+		UpdateColumns(&address).Error; err != nil {
+		return false, fmt.Errorf("failed to re-encrypt address %s: %w", address.ID, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+func TestRekey(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		ctx := context.Background()
+		t.Cleanup(func() { setKeyring(testKeys) })
+
+		users := createUsers(t, repo, "first@example.com", "second@example.com")
+		if err := repo.CreateAddress(ctx, &model.Address{ID: "addr_1", UserID: users[0].ID, StreetName: "1 Main St"}); err != nil {
+			t.Fatalf("CreateAddress() = %v", err)
+		}
+
+		newKey := "new:" + randomKey()
+		setKeyring(newKey + "," + testKeys)
+		rewritten, err := repo.Rekey(ctx, 1)
+		if err != nil {
+			t.Fatalf("Rekey() = %v", err)
+		}
+		if rewritten != 3 {
+			t.Errorf("Rekey() rewrote %d rows, want 3", rewritten)
+		}
+
+		// Everything is readable without the old key
+		setKeyring(newKey)
+		user, err := repo.GetUserByEmail(ctx, "second@example.com")
+		if err != nil {
+			t.Fatalf("GetUserByEmail() = %v", err)
+		}
+		if user.ID != users[1].ID {
+			t.Errorf("GetUserByEmail() = %s, want %s", user.ID, users[1].ID)
+		}
+		address, err := repo.GetAddress(ctx, users[0].ID, "addr_1")
+		if err != nil {
+			t.Fatalf("GetAddress() = %v", err)
+		}
+		if address.StreetName != "1 Main St" {
+			t.Errorf("street name = %q after rekey", address.StreetName)
+		}
+
+		var stored string
+		if err := db.Table("users").Where("id = ?", users[0].ID).Pluck("email", &stored).Error; err != nil {
+			t.Fatalf("failed to read stored email: %v", err)
+		}
+		if !strings.HasPrefix(stored, "enc:") || strings.Contains(stored, "first@example.com") {
+			t.Errorf("stored email %q is not encrypted", stored)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"gorm.io/gorm"
+)
+
+// AddReputationEvent appends an event to the reputation ledger and applies
+// its delta to the user's reputation
+func (r *userRepository) AddReputationEvent(ctx context.Context, event *model.ReputationEvent) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).Where("id = ?", event.UserID).
+			Update("reputation", gorm.Expr("reputation + ?", event.Delta))
+		if result.Error != nil {
+			return fmt.Errorf("failed to update reputation: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrUserNotFound
+		}
+
+		if err := tx.Create(event).Error; err != nil {
+			return fmt.Errorf("failed to record reputation event: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetReputationEvents retrieves the user's reputation ledger, oldest first
+func (r *userRepository) GetReputationEvents(ctx context.Context, userID string) ([]*model.ReputationEvent, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var events []*model.ReputationEvent
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get reputation events: %w", err)
+	}
+	return events, nil
+}
+
+// CountReputationEventsByReason returns how many ledger events the user has per reason
+func (r *userRepository) CountReputationEventsByReason(ctx context.Context, userID string) (map[string]int64, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	var rows []struct {
+		Reason string
+		Count  int64
+	}
+	if err := db.Model(&model.ReputationEvent{}).Select("reason, COUNT(*) AS count").
+		Where("user_id = ?", userID).Group("reason").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reputation events: %w", err)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Reason] = row.Count
+	}
+	return counts, nil
+}
+
+// RefreshLeaderboards rebuilds the leaderboard aggregates for every period.
+// All-time points come from the users' reputation; the other periods only
+// read the ledger entries created since the period started.
+func (r *userRepository) RefreshLeaderboards(ctx context.Context, now time.Time) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&model.LeaderboardEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to clear leaderboards: %w", err)
+		}
+
+		if err := tx.Exec(`INSERT INTO leaderboard_entries (period, user_id, name, state, locality, points, refreshed_at)
+			SELECT ?, id, name, state, locality, reputation, ? FROM users
+			WHERE reputation > 0 AND is_banned = ? AND deleted_at IS NULL`,
+			model.LeaderboardAllTime, now, false).Error; err != nil {
+			return fmt.Errorf("failed to refresh %s leaderboard: %w", model.LeaderboardAllTime, err)
+		}
+
+		for _, period := range model.LeaderboardPeriods {
+			if period == model.LeaderboardAllTime {
+				continue
+			}
+			since, err := model.LeaderboardPeriodStart(period, now)
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec(`INSERT INTO leaderboard_entries (period, user_id, name, state, locality, points, refreshed_at)
+				SELECT ?, u.id, u.name, u.state, u.locality, SUM(e.delta), ?
+				FROM reputation_events e JOIN users u ON u.id = e.user_id
+				WHERE e.created_at >= ? AND u.is_banned = ? AND u.deleted_at IS NULL
+				GROUP BY u.id, u.name, u.state, u.locality
+				HAVING SUM(e.delta) > 0`,
+				period, now, since, false).Error; err != nil {
+				return fmt.Errorf("failed to refresh %s leaderboard: %w", period, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetLeaderboard returns a page of the precomputed leaderboard, highest
+// points first
+func (r *userRepository) GetLeaderboard(ctx context.Context, filter model.LeaderboardFilter) ([]*model.LeaderboardEntry, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if _, err := model.LeaderboardPeriodStart(filter.Period, time.Now()); err != nil {
+		return nil, err
+	}
+
+	query := db.Where("period = ?", filter.Period)
+	if filter.State != "" {
+		query = query.Where("state = ?", filter.State)
+	}
+	if filter.Locality != "" {
+		query = query.Where("locality = ?", filter.Locality)
+	}
+
+	var entries []*model.LeaderboardEntry
+	if err := query.Order("points DESC").Order("user_id").
+		Offset(filter.Offset).Limit(filter.Limit).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+	return entries, nil
+}
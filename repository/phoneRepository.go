@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/liju-github/EcommerceUserService/encryption"
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+// UpdatePhoneVerification stores the phone number and OTP state of a user
+func (r *userRepository) UpdatePhoneVerification(ctx context.Context, user *model.User) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if err := user.RefreshBlindIndexes(); err != nil {
+		return err
+	}
+	err := updateVersioned(db, user, "update phone verification", "phone_number", "phone_index", "is_phone_verified", "pending_phone_number",
+		"phone_otp_hash", "phone_otp_expires_at", "phone_otp_attempts")
+	// Another account verified the number after IsPhoneVerifiedByOtherUser
+	// checked it
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return model.ErrDuplicatePhone
+	}
+	return err
+}
+
+// IsPhoneVerifiedByOtherUser reports whether another account has already
+// verified the phone number
+func (r *userRepository) IsPhoneVerifiedByOtherUser(ctx context.Context, phoneNumber, userID string) (bool, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	phoneIndex, err := encryption.BlindIndex(phoneNumber)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := db.Model(&model.User{}).
+		Where("phone_index = ? AND is_phone_verified = ? AND id <> ?", phoneIndex, true, userID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check phone number: %w", err)
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTxAttempts  = 5
+	txRetryBackoff = 20 * time.Millisecond
+)
+
+// WithinTx runs fn in a serializable transaction, passing it a repository
+// bound to that transaction. The transaction commits if fn returns nil and
+// rolls back otherwise. When the database reports a busy, deadlock or
+// serialization error the whole transaction is retried, so fn must not have
+// side effects outside the database. Calls nested in a transaction join it.
+func (r *userRepository) WithinTx(ctx context.Context, fn func(tx UserRepository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(&userRepository{db: tx, queryTimeout: r.queryTimeout, inTx: true})
+		}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		if err == nil || attempt == maxTxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryable reports whether err means the transaction lost a race with
+// another one and can succeed when run again
+func isRetryable(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure, deadlock_detected
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"sqlite busy", sqlite3.Error{Code: sqlite3.ErrBusy}, true},
+		{"sqlite locked", sqlite3.Error{Code: sqlite3.ErrLocked}, true},
+		{"sqlite constraint", sqlite3.Error{Code: sqlite3.ErrConstraint}, false},
+		{"postgres serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"postgres deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"postgres unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"mysql deadlock", &mysqldriver.MySQLError{Number: 1213}, true},
+		{"mysql lock wait timeout", &mysqldriver.MySQLError{Number: 1205}, true},
+		{"mysql duplicate entry", &mysqldriver.MySQLError{Number: 1062}, false},
+		{"wrapped", fmt.Errorf("failed to update user: %w", &pgconn.PgError{Code: "40001"}), true},
+		{"duplicate key", gorm.ErrDuplicatedKey, false},
+		{"other", errors.New("boom"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// auditEntries counts the audit log entries written by the tests
+func auditEntries(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&model.AuditLog{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count audit log: %v", err)
+	}
+	return count
+}
+
+func TestWithinTxRetriesRetryableErrors(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+
+		attempts := 0
+		err := repo.WithinTx(context.Background(), func(tx UserRepository) error {
+			attempts++
+			if err := tx.CreateAuditLog(context.Background(), &model.AuditLog{Action: "test", TargetUserID: "u1"}); err != nil {
+				return err
+			}
+			if attempts < 3 {
+				return sqlite3.Error{Code: sqlite3.ErrBusy}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithinTx() = %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("attempts = %d, want 3", attempts)
+		}
+		// The writes of the failed attempts were rolled back
+		if got := auditEntries(t, db); got != 1 {
+			t.Errorf("audit log has %d entries, want 1", got)
+		}
+	})
+}
+
+func TestWithinTxGivesUp(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		retryable := &pgconn.PgError{Code: "40001"}
+
+		tests := []struct {
+			name         string
+			err          error
+			wantAttempts int
+		}{
+			{"after max attempts", retryable, maxTxAttempts},
+			{"on other errors", model.ErrUserNotFound, 1},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				attempts := 0
+				err := repo.WithinTx(context.Background(), func(tx UserRepository) error {
+					attempts++
+					return tt.err
+				})
+				if !errors.Is(err, tt.err) {
+					t.Errorf("WithinTx() = %v, want %v", err, tt.err)
+				}
+				if attempts != tt.wantAttempts {
+					t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+				}
+			})
+		}
+	})
+}
+
+func TestWithinTxStopsRetryingWhenCancelled(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		attempts := 0
+		err := repo.WithinTx(ctx, func(tx UserRepository) error {
+			attempts++
+			cancel()
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("WithinTx() = %v, want %v", err, context.Canceled)
+		}
+		if attempts != 1 {
+			t.Errorf("attempts = %d, want 1", attempts)
+		}
+	})
+}
+
+func TestWithinTxNestedCallsJoin(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		ctx := context.Background()
+
+		err := repo.WithinTx(ctx, func(tx UserRepository) error {
+			if err := tx.WithinTx(ctx, func(tx UserRepository) error {
+				return tx.CreateAuditLog(ctx, &model.AuditLog{Action: "test", TargetUserID: "u1"})
+			}); err != nil {
+				return err
+			}
+			return model.ErrUserNotFound
+		})
+		if !errors.Is(err, model.ErrUserNotFound) {
+			t.Fatalf("WithinTx() = %v, want %v", err, model.ErrUserNotFound)
+		}
+		// The inner write rolled back with the outer transaction
+		if got := auditEntries(t, db); got != 0 {
+			t.Errorf("audit log has %d entries, want 0", got)
+		}
+	})
+}
+
+// TestWithinTxRetriesLostRaces has two transactions read and then update the
+// same user. The database aborts one of them with a serialization failure or
+// deadlock, which WithinTx retries so that neither update is lost.
+func TestWithinTxRetriesLostRaces(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		if db.Dialector.Name() == "sqlite" {
+			t.Skip("SQLite runs one transaction at a time on its single connection")
+		}
+		repo := NewUserRepository(db, 10*time.Second)
+		ctx := context.Background()
+
+		user := &model.User{ID: "usr_race", Email: "race@example.com", Version: 1}
+		if err := repo.CreateUser(ctx, user); err != nil {
+			t.Fatalf("CreateUser() = %v", err)
+		}
+
+		var read sync.WaitGroup
+		read.Add(2)
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		retried := make([]bool, 2)
+		for i, name := range []string{"first", "second"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				attempt := 0
+				errs[i] = repo.WithinTx(ctx, func(tx UserRepository) error {
+					attempt++
+					retried[i] = attempt > 1
+					user, err := tx.GetUserByID(ctx, "usr_race")
+					if err != nil {
+						return err
+					}
+					// Both transactions read before either writes
+					if attempt == 1 {
+						read.Done()
+						read.Wait()
+					}
+					user.Name = name
+					return tx.UpdateUser(ctx, user)
+				})
+			}()
+		}
+		wg.Wait()
+
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("transaction %d: WithinTx() = %v", i, err)
+			}
+		}
+		if !retried[0] && !retried[1] {
+			t.Error("neither transaction was retried")
+		}
+		got, err := repo.GetUserByID(ctx, "usr_race")
+		if err != nil {
+			t.Fatalf("GetUserByID() = %v", err)
+		}
+		if got.Version != 3 {
+			t.Errorf("version = %d, want 3 after both updates", got.Version)
+		}
+	})
+}
+
+// TestWithinTxRetriesBusySQLite holds the write lock of a SQLite file from a
+// second connection, so writes fail with SQLITE_BUSY until it is released.
+func TestWithinTxRetriesBusySQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.sqlite3")
+	// Without a busy timeout a locked database fails at once instead of waiting
+	db := openTestDB(t, sqlite.Open("file:"+path+"?_busy_timeout=0"))
+	repo := NewUserRepository(db, 5*time.Second)
+	ctx := context.Background()
+
+	holder, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatalf("failed to open second connection: %v", err)
+	}
+	defer holder.Close()
+	lock, err := holder.Conn(ctx)
+	if err != nil {
+		t.Fatalf("failed to open second connection: %v", err)
+	}
+	defer lock.Close()
+	if _, err := lock.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("failed to lock database: %v", err)
+	}
+	time.AfterFunc(50*time.Millisecond, func() {
+		lock.ExecContext(ctx, "ROLLBACK")
+	})
+
+	attempts := 0
+	err = repo.WithinTx(ctx, func(tx UserRepository) error {
+		attempts++
+		err := tx.CreateAuditLog(ctx, &model.AuditLog{Action: "test", TargetUserID: "u1"})
+		if attempts == 1 && !isRetryable(err) {
+			t.Errorf("first attempt failed with %v, want a retryable error", err)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() = %v", err)
+	}
+	if attempts < 2 {
+		t.Errorf("attempts = %d, want a retry", attempts)
+	}
+	if got := auditEntries(t, db); got != 1 {
+		t.Errorf("audit log has %d entries, want 1", got)
+	}
+}
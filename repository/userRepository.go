@@ -1,58 +1,181 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	model "github.com/liju-github/EcommerceUserService/models"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	CreateUser(user *model.User) error
-	GetUserByEmail(email string) (*model.User, error)
-	GetUserByID(id string) (*model.User, error)
-	UpdateUserVerification(userID string, isVerified bool) error
-	GetUserProfile(userID string) (*model.User, error)
-	UpdateUser(user *model.User) error
-	StoreVerificationCode(userID, code string) error
-	GetVerificationCode(userID string) (string, error)
-	CheckBan(userID string)(bool,error)
-	UnBanUser(userID string)(error)
-	BanUser(userID string) (error)
-	GetAllUsers()([]*model.User,error)
+	// transactions
+	WithinTx(ctx context.Context, fn func(tx UserRepository) error) error
+
+	CreateUser(ctx context.Context, user *model.User) error
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	UpdateUserVerification(ctx context.Context, userID string, isVerified bool) error
+	GetUserProfile(ctx context.Context, userID string) (*model.User, error)
+	UpdateUser(ctx context.Context, user *model.User) error
+	StoreVerificationCode(ctx context.Context, userID, code string) error
+	GetVerificationCode(ctx context.Context, userID string) (string, error)
+	CheckBan(ctx context.Context, userID string) (bool, error)
+	UnBanUser(ctx context.Context, userID string) error
+	BanUser(ctx context.Context, userID string) error
+	GetAllUsers(ctx context.Context) ([]*model.User, error)
+
+	// reputation ledger and leaderboards
+	AddReputationEvent(ctx context.Context, event *model.ReputationEvent) error
+	RefreshLeaderboards(ctx context.Context, now time.Time) error
+	GetLeaderboard(ctx context.Context, filter model.LeaderboardFilter) ([]*model.LeaderboardEntry, error)
+	CountReputationEventsByReason(ctx context.Context, userID string) (map[string]int64, error)
+	GetReputationEvents(ctx context.Context, userID string) ([]*model.ReputationEvent, error)
+
+	// badges
+	AwardBadges(ctx context.Context, badges []*model.UserBadge) error
+	GetUserBadges(ctx context.Context, userID string) ([]*model.UserBadge, error)
+
+	// address book
+	CreateAddress(ctx context.Context, address *model.Address) error
+	GetAddress(ctx context.Context, userID, addressID string) (*model.Address, error)
+	ListAddresses(ctx context.Context, userID string) ([]*model.Address, error)
+	CountAddresses(ctx context.Context, userID string) (int64, error)
+	UpdateAddress(ctx context.Context, address *model.Address) error
+	DeleteAddress(ctx context.Context, userID, addressID string) error
+	SetDefaultAddress(ctx context.Context, userID, addressID, usage string) error
+
+	// phone verification
+	UpdatePhoneVerification(ctx context.Context, user *model.User) error
+	IsPhoneVerifiedByOtherUser(ctx context.Context, phoneNumber, userID string) (bool, error)
+
+	// email change
+	UpdateEmailChange(ctx context.Context, user *model.User) error
+
+	// account deletion
+	SoftDeleteUser(ctx context.Context, userID string, purgeAfter time.Time) error
+	RestoreUser(ctx context.Context, userID string, now time.Time) error
+	PurgeDeletedUsers(ctx context.Context, now time.Time) (int, error)
+
+	// audit log and login history
+	CreateAuditLog(ctx context.Context, entry *model.AuditLog) error
+	GetAuditLogs(ctx context.Context, userID string, actions ...string) ([]*model.AuditLog, error)
+	CreateLoginEvent(ctx context.Context, event *model.LoginEvent) error
+	GetLoginEvents(ctx context.Context, userID string) ([]*model.LoginEvent, error)
+
+	// consents
+	CreateConsent(ctx context.Context, consent *model.Consent) error
+	GetConsents(ctx context.Context, userID string) ([]*model.Consent, error)
+	GetActiveConsent(ctx context.Context, userID, policyType string) (*model.Consent, error)
+	WithdrawConsents(ctx context.Context, userID, policyType string, at time.Time) error
+
+	// encryption
+	Rekey(ctx context.Context, batchSize int) (int, error)
 }
 
 type userRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	queryTimeout time.Duration
+	inTx         bool
+}
+
+// NewUserRepository returns a repository whose calls are each bounded by
+// queryTimeout on top of the caller's context.
+func NewUserRepository(db *gorm.DB, queryTimeout time.Duration) UserRepository {
+	return &userRepository{db: db, queryTimeout: queryTimeout}
 }
 
-func NewUserRepository(db *gorm.DB) UserRepository {
-	return &userRepository{db: db}
+// withContext binds the database handle to ctx with the query timeout applied
+func (r *userRepository) withContext(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	return r.db.WithContext(ctx), cancel
 }
 
-func (r *userRepository) GetAllUsers() ([]*model.User, error) {
+// updateVersioned writes columns of user only if the stored version still
+// matches user.Version, and bumps the version. A user changed since it was
+// read gets ErrVersionConflict; database errors are wrapped with action.
+func updateVersioned(db *gorm.DB, user *model.User, action string, columns ...string) error {
+	expected := user.Version
+	user.Version++
+	result := db.Model(&model.User{}).Where("id = ? AND version = ?", user.ID, expected).
+		Select(append(columns, "version")).
+		Updates(user)
+	if result.Error != nil {
+		user.Version = expected
+		return fmt.Errorf("failed to %s: %w", action, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		user.Version = expected
+		var count int64
+		if err := db.Model(&model.User{}).Where("id = ?", user.ID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to %s: %w", action, err)
+		}
+		if count == 0 {
+			return model.ErrUserNotFound
+		}
+		return model.ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *userRepository) GetAllUsers(ctx context.Context) ([]*model.User, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
 	var users []*model.User
-	if err := r.db.Find(&users).Error; err != nil {
+	if err := db.Find(&users).Error; err != nil {
 		return nil, fmt.Errorf("failed to get all users: %w", err)
 	}
 	return users, nil
 }
 
 // CreateUser creates a new user record
-func (r *userRepository) CreateUser(user *model.User) error {
-	if err := r.db.Create(user).Error; err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+func (r *userRepository) CreateUser(ctx context.Context, user *model.User) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if err := user.RefreshBlindIndexes(); err != nil {
+		return err
 	}
-	return nil
+	// The insert gets its own savepoint inside a transaction, so that the
+	// lookup below still runs on Postgres after a unique violation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(user).Error
+	})
+	if err == nil {
+		return nil
+	}
+	// The ID and the phone index are unique too, only a taken email index
+	// means the address is registered
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		var count int64
+		if lookupErr := db.Unscoped().Model(&model.User{}).Where("email_index = ?", user.EmailIndex).Count(&count).Error; lookupErr != nil {
+			return fmt.Errorf("failed to create user: %w", lookupErr)
+		}
+		if count > 0 {
+			return model.ErrDuplicateEmail
+		}
+	}
+	return fmt.Errorf("failed to create user: %w", err)
 }
 
-// GetUserByEmail retrieves a user by their email address
-func (r *userRepository) GetUserByEmail(email string) (*model.User, error) {
+// GetUserByEmail retrieves a user by any spelling of their email address
+// through its blind index
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	emailIndex, err := model.EmailIndex(email)
+	if err != nil {
+		return nil, err
+	}
+
 	var user model.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := db.Where("email_index = ?", emailIndex).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, model.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -60,12 +183,14 @@ func (r *userRepository) GetUserByEmail(email string) (*model.User, error) {
 }
 
 // GetUserByID retrieves a user by their ID
-func (r *userRepository) GetUserByID(id string) (*model.User, error) {
+func (r *userRepository) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
 
 	var user model.User
-	if err := r.db.Where("id = ?", id).First(&user).Error; err != nil {
+	if err := db.Where("id = ?", id).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, model.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
@@ -73,119 +198,135 @@ func (r *userRepository) GetUserByID(id string) (*model.User, error) {
 }
 
 // UpdateUserVerification updates the verification status of a user
-func (r *userRepository) UpdateUserVerification(userID string, isVerified bool) error {
-	result := r.db.Model(&model.User{}).Where("id = ?", userID).Update("is_verified", isVerified)
+func (r *userRepository) UpdateUserVerification(ctx context.Context, userID string, isVerified bool) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	result := db.Model(&model.User{}).Where("id = ?", userID).Update("is_verified", isVerified)
 	if result.Error != nil {
 		return fmt.Errorf("failed to update user verification: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return model.ErrUserNotFound
 	}
 	return nil
 }
 
 // GetUserProfile retrieves the user profile by userID
-func (r *userRepository) GetUserProfile(userID string) (*model.User, error) {
+func (r *userRepository) GetUserProfile(ctx context.Context, userID string) (*model.User, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
 	var user model.User
-	if err := r.db.Select("id, email, name, street_name, locality, state, pincode, phone_number, reputation, is_verified").
+	if err := db.Select("id, email, name, street_name, locality, state, pincode, phone_number, reputation, is_verified, is_phone_verified, version").
 		Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, model.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user profile: %w", err)
 	}
 	return &user, nil
 }
 
-// UpdateUser updates a user's information
-func (r *userRepository) UpdateUser(user *model.User) error {
-	result := r.db.Model(&model.User{}).Where("id = ?", user.ID).
-		Updates(map[string]interface{}{
-			"name":        user.Name,
-			"street_name": user.StreetName,
-			"locality":    user.Locality,
-			"state":       user.State,
-			"pincode":     user.Pincode,
-			"phone_number": user.PhoneNumber,
-		})
-	if result.Error != nil {
-		return fmt.Errorf("failed to update user: %w", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+// UpdateUser updates a user's information unless it changed since it was read
+func (r *userRepository) UpdateUser(ctx context.Context, user *model.User) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	if err := user.RefreshBlindIndexes(); err != nil {
+		return err
 	}
-	return nil
+	return updateVersioned(db, user, "update user", "name", "street_name", "locality", "state", "pincode", "phone_number", "phone_index", "is_phone_verified")
 }
 
 // StoreVerificationCode stores the verification code for a user
-func (r *userRepository) StoreVerificationCode(userID, code string) error {
-	result := r.db.Model(&model.User{}).Where("id = ?", userID).Update("verification_code", code)
+func (r *userRepository) StoreVerificationCode(ctx context.Context, userID, code string) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
+	result := db.Model(&model.User{}).Where("id = ?", userID).Update("verification_code", code)
 	if result.Error != nil {
 		return fmt.Errorf("failed to store verification code: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return model.ErrUserNotFound
 	}
 	return nil
 }
 
 // GetVerificationCode retrieves the verification code for a user
-func (r *userRepository) GetVerificationCode(userID string) (string, error) {
+func (r *userRepository) GetVerificationCode(ctx context.Context, userID string) (string, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
 	var user model.User
-	if err := r.db.Select("verification_code").Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Select("verification_code").Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("user not found")
+			return "", model.ErrUserNotFound
 		}
 		return "", fmt.Errorf("failed to get verification code: %w", err)
 	}
 	return user.VerificationCode, nil
 }
 
-func (r *userRepository)CheckBan(userID string) (bool,error){
+func (r *userRepository) CheckBan(ctx context.Context, userID string) (bool, error) {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
 	var user model.User
-	if err:=r.db.Select("is_banned").Where("id = ?", userID).First(&user).Error;err!=nil{
-		return true, errors.New("check ban failed")
+	if err := db.Select("is_banned").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, model.ErrUserNotFound
+		}
+		return true, fmt.Errorf("failed to check ban: %w", err)
 	}
-	if user.IsBanned{
-		return true,nil
+	if user.IsBanned {
+		return true, nil
 	}
-	return false,nil
+	return false, nil
 }
 
-func (r *userRepository) BanUser(userID string) error {
+func (r *userRepository) BanUser(ctx context.Context, userID string) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
 	var user model.User
-	if err := r.db.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
+			return model.ErrUserNotFound
 		}
 		return fmt.Errorf("failed to find user: %w", err)
 	}
 
-	result := r.db.Model(&user).Update("is_banned", true)
+	// Update also bumps updated_at, so only the condition on is_banned
+	// leaves an already banned user untouched
+	result := db.Model(&user).Where("is_banned = ?", false).Update("is_banned", true)
 	if result.Error != nil {
 		return fmt.Errorf("failed to ban user: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("no rows affected, user may already be banned")
+		return model.ErrAlreadyBanned
 	}
 
 	return nil
 }
 
-func (r *userRepository) UnBanUser(userID string) error {
+func (r *userRepository) UnBanUser(ctx context.Context, userID string) error {
+	db, cancel := r.withContext(ctx)
+	defer cancel()
+
 	// Check if the user exists
 	var user model.User
-	if err := r.db.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
+			return model.ErrUserNotFound
 		}
 		return fmt.Errorf("failed to find user: %w", err)
 	}
 
-	if err := r.db.Model(&user).Updates(map[string]interface{}{"is_banned": false,"id":userID}).Error; err != nil {
+	if err := db.Model(&user).Updates(map[string]interface{}{"is_banned": false, "id": userID}).Error; err != nil {
 		return fmt.Errorf("failed to unban user: %w", err)
 	}
 
 	return nil
 }
-
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+// createUsers stores a user per email address, with IDs in the same order
+func createUsers(t *testing.T, repo UserRepository, emails ...string) []*model.User {
+	t.Helper()
+	users := make([]*model.User, len(emails))
+	for i, email := range emails {
+		users[i] = &model.User{ID: "usr_" + string(rune('a'+i)), Email: email, Version: 1}
+		if err := repo.CreateUser(context.Background(), users[i]); err != nil {
+			t.Fatalf("CreateUser(%s) = %v", email, err)
+		}
+	}
+	return users
+}
+
+// The unique violations of every driver reach the repository as
+// gorm.ErrDuplicatedKey through TranslateError and are mapped to the
+// service's errors.
+
+func TestCreateUserDuplicateEmail(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		createUsers(t, repo, "jane.doe@gmail.com")
+
+		for _, email := range []string{"jane.doe@gmail.com", " Jane.Doe@Gmail.com", "janedoe+shop@gmail.com"} {
+			err := repo.CreateUser(context.Background(), &model.User{ID: "usr_dup", Email: email, Version: 1})
+			if !errors.Is(err, model.ErrDuplicateEmail) {
+				t.Errorf("CreateUser(%q) = %v, want %v", email, err, model.ErrDuplicateEmail)
+			}
+		}
+	})
+}
+
+func TestCreateUserDuplicateID(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		users := createUsers(t, repo, "first@example.com")
+
+		err := repo.CreateUser(context.Background(), &model.User{ID: users[0].ID, Email: "second@example.com", Version: 1})
+		if !errors.Is(err, gorm.ErrDuplicatedKey) || errors.Is(err, model.ErrDuplicateEmail) {
+			t.Errorf("CreateUser() = %v, want %v and not %v", err, gorm.ErrDuplicatedKey, model.ErrDuplicateEmail)
+		}
+	})
+}
+
+func TestUpdateEmailChangeDuplicateEmail(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		users := createUsers(t, repo, "first@example.com", "second@example.com")
+
+		users[1].Email = "FIRST@example.com"
+		err := repo.UpdateEmailChange(context.Background(), users[1])
+		if !errors.Is(err, model.ErrDuplicateEmail) {
+			t.Errorf("UpdateEmailChange() = %v, want %v", err, model.ErrDuplicateEmail)
+		}
+	})
+}
+
+func TestUpdatePhoneVerificationDuplicatePhone(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		ctx := context.Background()
+		users := createUsers(t, repo, "first@example.com", "second@example.com", "third@example.com")
+
+		for _, user := range users {
+			user.PhoneNumber = "+919876543210"
+		}
+		users[0].IsPhoneVerified = true
+		if err := repo.UpdatePhoneVerification(ctx, users[0]); err != nil {
+			t.Fatalf("UpdatePhoneVerification() = %v", err)
+		}
+
+		// Unverified copies of a verified number are allowed
+		if err := repo.UpdatePhoneVerification(ctx, users[1]); err != nil {
+			t.Errorf("UpdatePhoneVerification() of an unverified number = %v", err)
+		}
+
+		users[2].IsPhoneVerified = true
+		err := repo.UpdatePhoneVerification(ctx, users[2])
+		if !errors.Is(err, model.ErrDuplicatePhone) {
+			t.Errorf("UpdatePhoneVerification() = %v, want %v", err, model.ErrDuplicatePhone)
+		}
+	})
+}
+
+func TestCreateAddressDuplicateID(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		ctx := context.Background()
+
+		address := &model.Address{ID: "addr_1", UserID: "usr_a", Label: "Home"}
+		if err := repo.CreateAddress(ctx, address); err != nil {
+			t.Fatalf("CreateAddress() = %v", err)
+		}
+		err := repo.CreateAddress(ctx, &model.Address{ID: "addr_1", UserID: "usr_a", Label: "Work"})
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Errorf("CreateAddress() = %v, want %v", err, gorm.ErrDuplicatedKey)
+		}
+	})
+}
+
+func TestGetUserNotFound(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+
+		if _, err := repo.GetUserByID(context.Background(), "usr_missing"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetUserByID() = %v, want %v", err, model.ErrUserNotFound)
+		}
+		if _, err := repo.GetUserByEmail(context.Background(), "missing@example.com"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetUserByEmail() = %v, want %v", err, model.ErrUserNotFound)
+		}
+	})
+}
+
+func TestBanUserTwice(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepository(db, 5*time.Second)
+		ctx := context.Background()
+		users := createUsers(t, repo, "first@example.com")
+
+		if err := repo.BanUser(ctx, users[0].ID); err != nil {
+			t.Fatalf("BanUser() = %v", err)
+		}
+		if err := repo.BanUser(ctx, users[0].ID); !errors.Is(err, model.ErrAlreadyBanned) {
+			t.Errorf("second BanUser() = %v, want %v", err, model.ErrAlreadyBanned)
+		}
+		if err := repo.BanUser(ctx, "usr_missing"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("BanUser() of a missing user = %v, want %v", err, model.ErrUserNotFound)
+		}
+	})
+}
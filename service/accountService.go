@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	"github.com/liju-github/EcommerceUserService/repository"
+)
+
+// DeleteAccount soft-deletes the account. It can be restored until the
+// grace period ends, after which the purger anonymizes it.
+func (s *UserService) DeleteAccount(ctx context.Context, req *userPb.DeleteAccountRequest) (*userPb.DeleteAccountResponse, error) {
+	purgeAfter := time.Now().Add(s.deletionGrace)
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if err := tx.SoftDeleteUser(ctx, req.UserId, purgeAfter); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditActor(ctx), model.AuditActionDeleteAccount, req.UserId, map[string]interface{}{
+			"purgeAfter": purgeAfter.UTC().Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.DeleteAccountResponse{
+		Success:    true,
+		Message:    "Account scheduled for deletion",
+		PurgeAfter: purgeAfter.Unix(),
+	}, nil
+}
+
+// RestoreAccount undoes DeleteAccount during the grace period
+func (s *UserService) RestoreAccount(ctx context.Context, req *userPb.RestoreAccountRequest) (*userPb.RestoreAccountResponse, error) {
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if err := tx.RestoreUser(ctx, req.UserId, time.Now()); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditActor(ctx), model.AuditActionRestoreAccount, req.UserId, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.RestoreAccountResponse{
+		Success: true,
+		Message: "Account restored",
+	}, nil
+}
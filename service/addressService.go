@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"github.com/liju-github/EcommerceUserService/postal"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	"github.com/liju-github/EcommerceUserService/repository"
+	util "github.com/liju-github/EcommerceUserService/utils"
+)
+
+const (
+	maxAddressesPerUser = 20
+	addressIDPrefix     = "addr_"
+)
+
+// AddAddress adds an address to the user's address book. The first address
+// becomes the default for both shipping and billing.
+func (s *UserService) AddAddress(ctx context.Context, req *userPb.AddAddressRequest) (*userPb.AddressResponse, error) {
+	address := fromAddressRequest(req.UserId, req.GetAddress())
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if _, err := tx.GetUserByID(ctx, req.UserId); err != nil {
+			return err
+		}
+
+		count, err := tx.CountAddresses(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+		if count >= maxAddressesPerUser {
+			return model.ErrAddressLimitReached
+		}
+
+		if address.State, err = normalizeAddress(address.Pincode, address.State); err != nil {
+			return err
+		}
+		if address.Phone != "" {
+			if address.Phone, err = util.NormalizePhone(address.Phone, s.phoneRegion); err != nil {
+				return &model.FieldError{Field: "address.phone", Err: err}
+			}
+		}
+		address.ID = s.ids.NewID(addressIDPrefix)
+		address.IsDefaultShipping = count == 0
+		address.IsDefaultBilling = count == 0
+
+		if err := tx.CreateAddress(ctx, address); err != nil {
+			return fmt.Errorf("failed to add address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.AddressResponse{
+		Success: true,
+		Message: "Address added successfully",
+		Address: toAddressResponse(address),
+	}, nil
+}
+
+// UpdateAddress replaces the details of an existing address
+func (s *UserService) UpdateAddress(ctx context.Context, req *userPb.UpdateAddressRequest) (*userPb.AddressResponse, error) {
+	address := fromAddressRequest(req.UserId, req.GetAddress())
+	state, err := normalizeAddress(address.Pincode, address.State)
+	if err != nil {
+		return nil, err
+	}
+	address.State = state
+	if address.Phone != "" {
+		if address.Phone, err = util.NormalizePhone(address.Phone, s.phoneRegion); err != nil {
+			return nil, &model.FieldError{Field: "address.phone", Err: err}
+		}
+	}
+
+	var updated *model.Address
+	err = s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if err := tx.UpdateAddress(ctx, address); err != nil {
+			return err
+		}
+		var err error
+		updated, err = tx.GetAddress(ctx, req.UserId, address.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.AddressResponse{
+		Success: true,
+		Message: "Address updated successfully",
+		Address: toAddressResponse(updated),
+	}, nil
+}
+
+// DeleteAddress removes an address from the user's address book
+func (s *UserService) DeleteAddress(ctx context.Context, req *userPb.DeleteAddressRequest) (*userPb.DeleteAddressResponse, error) {
+	if err := s.repo.DeleteAddress(ctx, req.UserId, req.AddressId); err != nil {
+		return nil, err
+	}
+
+	return &userPb.DeleteAddressResponse{
+		Success: true,
+		Message: "Address deleted successfully",
+	}, nil
+}
+
+// ListAddresses returns the user's address book
+func (s *UserService) ListAddresses(ctx context.Context, req *userPb.ListAddressesRequest) (*userPb.ListAddressesResponse, error) {
+	addresses, err := s.repo.ListAddresses(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &userPb.ListAddressesResponse{}
+	for _, address := range addresses {
+		response.Addresses = append(response.Addresses, toAddressResponse(address))
+	}
+	return response, nil
+}
+
+// SetDefaultAddress makes an address the default for shipping or billing
+func (s *UserService) SetDefaultAddress(ctx context.Context, req *userPb.SetDefaultAddressRequest) (*userPb.AddressResponse, error) {
+	var address *model.Address
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if err := tx.SetDefaultAddress(ctx, req.UserId, req.AddressId, req.Usage); err != nil {
+			return err
+		}
+		var err error
+		address, err = tx.GetAddress(ctx, req.UserId, req.AddressId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.AddressResponse{
+		Success: true,
+		Message: "Default address updated successfully",
+		Address: toAddressResponse(address),
+	}, nil
+}
+
+// LookupPincode returns the district and state a pincode belongs to so
+// clients can autofill address forms
+func (s *UserService) LookupPincode(ctx context.Context, req *userPb.LookupPincodeRequest) (*userPb.LookupPincodeResponse, error) {
+	info, err := postal.Lookup(req.Pincode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.LookupPincodeResponse{
+		Pincode:   info.Pincode,
+		District:  info.District,
+		State:     info.StateName,
+		StateCode: info.StateCode,
+	}, nil
+}
+
+// normalizeAddress validates a pincode and state pair and returns the
+// canonical state code. Either value may be empty.
+func normalizeAddress(pincode, state string) (string, error) {
+	if pincode == "" && state == "" {
+		return "", nil
+	}
+	var err error
+	if pincode == "" {
+		state, err = postal.NormalizeState(state)
+	} else {
+		state, err = postal.Validate(pincode, state)
+	}
+	switch {
+	case err == nil:
+		return state, nil
+	case errors.Is(err, postal.ErrUnknownState):
+		return "", &model.FieldError{Field: "state", Err: err}
+	default:
+		return "", &model.FieldError{Field: "pincode", Err: err}
+	}
+}
+
+func fromAddressRequest(userID string, address *userPb.Address) *model.Address {
+	return &model.Address{
+		ID:            address.GetId(),
+		UserID:        userID,
+		Label:         address.GetLabel(),
+		RecipientName: address.GetRecipientName(),
+		Phone:         address.GetPhone(),
+		StreetName:    address.GetStreetName(),
+		Locality:      address.GetLocality(),
+		Landmark:      address.GetLandmark(),
+		State:         address.GetState(),
+		Pincode:       address.GetPincode(),
+	}
+}
+
+func toAddressResponse(address *model.Address) *userPb.Address {
+	return &userPb.Address{
+		Id:                address.ID,
+		Label:             address.Label,
+		RecipientName:     address.RecipientName,
+		Phone:             address.Phone,
+		StreetName:        address.StreetName,
+		Locality:          address.Locality,
+		Landmark:          address.Landmark,
+		State:             address.State,
+		Pincode:           address.Pincode,
+		IsDefaultShipping: address.IsDefaultShipping,
+		IsDefaultBilling:  address.IsDefaultBilling,
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"github.com/liju-github/EcommerceUserService/repository"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// recordAudit appends an entry to the audit log. Actions taken in a
+// transaction pass its repository so the entry commits with them.
+func recordAudit(ctx context.Context, repo repository.UserRepository, actorID, action, targetUserID string, details map[string]interface{}) error {
+	entry := model.AuditLog{
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: targetUserID,
+	}
+	if len(details) > 0 {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		entry.Metadata = string(encoded)
+	}
+	return repo.CreateAuditLog(ctx, &entry)
+}
+
+// auditActor returns the authenticated end user or service identity of the
+// caller to record as the actor of an action, or AuditActorUnknown when the
+// call carried neither
+func auditActor(ctx context.Context) string {
+	if userID, ok := AuthenticatedUser(ctx); ok {
+		return userID
+	}
+	if identity, ok := CallerIdentity(ctx); ok {
+		return identity
+	}
+	return model.AuditActorUnknown
+}
+
+// clientIP returns the address of the caller, preferring the client address
+// forwarded by the gateway
+func clientIP(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-forwarded-for"); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// userAgent returns the caller's user agent, preferring the one forwarded by the gateway
+func userAgent(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range []string{"x-forwarded-user-agent", "user-agent"} {
+			if values := md.Get(key); len(values) > 0 {
+				return values[0]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	util "github.com/liju-github/EcommerceUserService/utils"
+)
+
+// publicMethods can be called through the gateway without logging in. Every
+// other call the gateway makes must carry the end user's token, and may only
+// touch that user.
+var publicMethods = map[string]bool{
+	userPb.UserService_Register_FullMethodName:       true,
+	userPb.UserService_Login_FullMethodName:          true,
+	userPb.UserService_VerifyEmail_FullMethodName:    true,
+	userPb.UserService_GetLeaderboard_FullMethodName: true,
+	userPb.UserService_ListBadges_FullMethodName:     true,
+	userPb.UserService_LookupPincode_FullMethodName:  true,
+}
+
+// GatewayUnaryInterceptor is the client interceptor of the gateway's
+// connection. It refuses to forward calls that lack a valid bearer token of
+// the user they are about, so the end user's token reaches the service with
+// every call that needs one.
+func GatewayUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if _, err := authenticateUser(md, method, req); err != nil {
+		return toStatus(ctx, err)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// authenticateUser checks that a call to a non-public method carries the
+// bearer token of the user named by the request's userId, and returns that
+// user's ID
+func authenticateUser(md metadata.MD, method string, req interface{}) (string, error) {
+	if publicMethods[method] {
+		return "", nil
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", model.ErrMissingToken
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return "", model.ErrMissingToken
+	}
+	claims, err := util.ValidateToken(token)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidToken) {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %v", model.ErrInvalidToken, err)
+	}
+
+	// Requests that name no user cannot be checked and are refused
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", model.ErrTokenUserMismatch
+	}
+	if userID, ok := requestUserID(msg); !ok || userID != claims.UserID {
+		return "", model.ErrTokenUserMismatch
+	}
+	return claims.UserID, nil
+}
+
+// requestUserID returns the userId (or userID) field of a request
+func requestUserID(req proto.Message) (string, bool) {
+	msg := req.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+	for _, name := range []protoreflect.Name{"userId", "userID"} {
+		if field := fields.ByName(name); field != nil && field.Kind() == protoreflect.StringKind {
+			return msg.Get(field).String(), true
+		}
+	}
+	return "", false
+}
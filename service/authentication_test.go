@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	util "github.com/liju-github/EcommerceUserService/utils"
+)
+
+func TestAuthenticateUser(t *testing.T) {
+	util.SetJWTSecretKey("test-secret")
+	token, err := util.GenerateToken(&model.User{ID: "usr_1"}, "user", nil)
+	if err != nil {
+		t.Fatalf("GenerateToken() = %v", err)
+	}
+	bearer := metadata.Pairs("authorization", "Bearer "+token)
+
+	tests := []struct {
+		name   string
+		md     metadata.MD
+		method string
+		req    interface{}
+		want   error
+	}{
+		{"public method", nil, userPb.UserService_Login_FullMethodName, &userPb.LoginRequest{}, nil},
+		{"own profile", bearer, userPb.UserService_GetProfile_FullMethodName, &userPb.ProfileRequest{UserId: "usr_1"}, nil},
+		{"own ban status", bearer, userPb.UserService_CheckBan_FullMethodName, &userPb.CheckBanRequest{UserID: "usr_1"}, nil},
+		{"other user's profile", bearer, userPb.UserService_GetProfile_FullMethodName,
+			&userPb.ProfileRequest{UserId: "usr_2"}, model.ErrTokenUserMismatch},
+		{"request without user", bearer, userPb.UserService_GetAllUsers_FullMethodName,
+			&userPb.GetAllUsersRequest{}, model.ErrTokenUserMismatch},
+		{"no token", nil, userPb.UserService_GetProfile_FullMethodName,
+			&userPb.ProfileRequest{UserId: "usr_1"}, model.ErrMissingToken},
+		{"not a bearer token", metadata.Pairs("authorization", "Basic dXNlcjpwYXNz"), userPb.UserService_GetProfile_FullMethodName,
+			&userPb.ProfileRequest{UserId: "usr_1"}, model.ErrMissingToken},
+		{"invalid token", metadata.Pairs("authorization", "Bearer junk"), userPb.UserService_GetProfile_FullMethodName,
+			&userPb.ProfileRequest{UserId: "usr_1"}, model.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authenticateUser(tt.md, tt.method, tt.req)
+			if !errors.Is(err, tt.want) || (err != nil) != (tt.want != nil) {
+				t.Errorf("authenticateUser() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+)
+
+// Service identities that restrictedMethods grants access to
+const (
+	IdentityAdmin   = "admin"
+	IdentityContent = "content"
+	// The gateway acts for end users, whose token it must forward
+	IdentityGateway = "gateway"
+)
+
+// restrictedMethods lists the identities allowed to call a method. Methods
+// not listed may be called by any known client, or by any client at all when
+// mutual TLS is off. Without mutual TLS restricted methods are refused,
+// unless unauthenticated admin RPCs were explicitly allowed.
+var restrictedMethods = map[string][]string{
+	userPb.UserService_BanUser_FullMethodName:        {IdentityAdmin},
+	userPb.UserService_UnBanUser_FullMethodName:      {IdentityAdmin},
+	userPb.UserService_GetAllUsers_FullMethodName:    {IdentityAdmin},
+	userPb.UserService_ExportUserData_FullMethodName: {IdentityAdmin},
+	userPb.UserService_DeleteAccount_FullMethodName:  {IdentityAdmin},
+	userPb.UserService_RestoreAccount_FullMethodName: {IdentityAdmin},
+
+	// Reputation drives privileges and badges, so only the content service
+	// may record the votes and answers it is earned through
+	userPb.UserService_RecordReputationEvent_FullMethodName: {IdentityContent},
+}
+
+// selfServiceMethods are restricted methods that users may also call on
+// their own account, by passing their bearer token as through the gateway
+var selfServiceMethods = map[string]bool{
+	userPb.UserService_DeleteAccount_FullMethodName:  true,
+	userPb.UserService_RestoreAccount_FullMethodName: true,
+}
+
+type identityKey struct{}
+
+type userKey struct{}
+
+// CallerIdentity returns the service identity of the client certificate the
+// call was made with, when mutual TLS is enabled.
+func CallerIdentity(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(identityKey{}).(string)
+	return identity, ok
+}
+
+// AuthenticatedUser returns the ID of the end user whose bearer token the
+// call was checked against
+func AuthenticatedUser(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userKey{}).(string)
+	return userID, ok
+}
+
+// Authorizer maps the SANs of verified client certificates to service
+// identities and checks UserService calls against restrictedMethods.
+type Authorizer struct {
+	identities map[string]string
+	mutualTLS  bool
+	// Lets callers without an identity call restricted methods, as before
+	// mutual TLS existed
+	allowUnauthenticatedAdmin bool
+}
+
+// NewAuthorizer creates an Authorizer from a map of certificate SAN (DNS
+// name or URI) to service identity. Without mutual TLS no caller has an
+// identity, and only the unrestricted and self-service methods can be
+// called, unless allowUnauthenticatedAdmin opens the restricted ones to
+// everyone.
+func NewAuthorizer(identities map[string]string, mutualTLS, allowUnauthenticatedAdmin bool) *Authorizer {
+	return &Authorizer{identities: identities, mutualTLS: mutualTLS, allowUnauthenticatedAdmin: allowUnauthenticatedAdmin}
+}
+
+// UnaryInterceptor rejects callers that are not allowed to call the method
+// and records the identity of the others for CallerIdentity. Calls from the
+// gateway must also carry the token of the user they are about. It must run
+// inside UnaryErrorInterceptor.
+func (a *Authorizer) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod, req)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor is UnaryInterceptor for streaming RPCs. The request of a
+// stream is read after it starts, so the gateway may only open public ones.
+func (a *Authorizer) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorize(ss.Context(), info.FullMethod, nil)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &identityStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize checks a call to method, whose request is req for unary calls
+// and nil for streams
+func (a *Authorizer) authorize(ctx context.Context, method string, req interface{}) (context.Context, error) {
+	// Health checks and reflection are open to every verified client
+	if !strings.HasPrefix(method, "/"+userPb.UserService_ServiceDesc.ServiceName+"/") {
+		return ctx, nil
+	}
+
+	identity, identified := a.identify(ctx)
+	if !identified && a.mutualTLS {
+		return ctx, model.ErrUnknownClient
+	}
+	if identified {
+		ctx = context.WithValue(ctx, identityKey{}, identity)
+	}
+
+	// The gateway acts for end users and must prove which one. Users may
+	// call the self-service methods on their own account with their token.
+	md, _ := metadata.FromIncomingContext(ctx)
+	selfService := selfServiceMethods[method] && len(md.Get("authorization")) > 0
+	if identity == IdentityGateway || selfService {
+		if req == nil {
+			if !publicMethods[method] {
+				return ctx, model.ErrCallerNotAllowed
+			}
+		} else {
+			userID, err := authenticateUser(md, method, req)
+			if err != nil {
+				return ctx, err
+			}
+			if userID != "" {
+				ctx = context.WithValue(ctx, userKey{}, userID)
+			}
+		}
+	}
+
+	allowed, restricted := restrictedMethods[method]
+	switch {
+	case !restricted || selfService:
+		return ctx, nil
+	case identified:
+		if !slices.Contains(allowed, identity) {
+			return ctx, model.ErrCallerNotAllowed
+		}
+		return ctx, nil
+	case a.allowUnauthenticatedAdmin:
+		return ctx, nil
+	default:
+		return ctx, model.ErrUnknownClient
+	}
+}
+
+// identify finds the identity of the first SAN of the client's leaf
+// certificate that has one
+func (a *Authorizer) identify(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	leaf := tlsInfo.State.VerifiedChains[0][0]
+	for _, uri := range leaf.URIs {
+		if identity, ok := a.identities[uri.String()]; ok {
+			return identity, true
+		}
+	}
+	for _, name := range leaf.DNSNames {
+		if identity, ok := a.identities[name]; ok {
+			return identity, true
+		}
+	}
+	return "", false
+}
+
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	util "github.com/liju-github/EcommerceUserService/utils"
+)
+
+// withClientCert returns a context for a call made with a verified client
+// certificate for the given SPIFFE URI
+func withClientCert(uri string) context.Context {
+	leaf := &x509.Certificate{URIs: []*url.URL{{Scheme: "spiffe", Host: "ecommerce", Path: uri}}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}},
+	}})
+}
+
+// withToken adds the bearer token of a user with the given ID to ctx, as the
+// gateway forwards it
+func withToken(t *testing.T, ctx context.Context, userID string) context.Context {
+	t.Helper()
+	util.SetJWTSecretKey("test-secret")
+	token, err := util.GenerateToken(&model.User{ID: userID}, "user", nil)
+	if err != nil {
+		t.Fatalf("GenerateToken() = %v", err)
+	}
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestAuthorize(t *testing.T) {
+	identities := map[string]string{
+		"spiffe://ecommerce/admin":   IdentityAdmin,
+		"spiffe://ecommerce/content": IdentityContent,
+		"spiffe://ecommerce/gateway": IdentityGateway,
+	}
+	anonymous := context.Background()
+	profile := &userPb.ProfileRequest{UserId: "usr_1"}
+	deleteAccount := &userPb.DeleteAccountRequest{UserId: "usr_1"}
+
+	tests := []struct {
+		name       string
+		mutualTLS  bool
+		allowAdmin bool
+		ctx        context.Context
+		method     string
+		req        interface{}
+		want       error
+	}{
+		{"open method without mutual TLS", false, false, anonymous, userPb.UserService_GetProfile_FullMethodName, profile, nil},
+		{"ban without mutual TLS", false, false, anonymous, userPb.UserService_BanUser_FullMethodName, &userPb.BanUserRequest{}, model.ErrUnknownClient},
+		{"export without mutual TLS", false, false, anonymous, userPb.UserService_ExportUserData_FullMethodName, nil, model.ErrUnknownClient},
+		{"delete account without mutual TLS", false, false, anonymous, userPb.UserService_DeleteAccount_FullMethodName, deleteAccount, model.ErrUnknownClient},
+		{"reputation without mutual TLS", false, false, anonymous, userPb.UserService_RecordReputationEvent_FullMethodName, &userPb.RecordReputationEventRequest{}, model.ErrUnknownClient},
+		{"ban with unauthenticated admin RPCs", false, true, anonymous, userPb.UserService_BanUser_FullMethodName, &userPb.BanUserRequest{}, nil},
+		{"export with unauthenticated admin RPCs", false, true, anonymous, userPb.UserService_ExportUserData_FullMethodName, nil, nil},
+		{"own account deletion without mutual TLS", false, false, withToken(t, anonymous, "usr_1"), userPb.UserService_DeleteAccount_FullMethodName, deleteAccount, nil},
+		{"own account restore without mutual TLS", false, false, withToken(t, anonymous, "usr_1"), userPb.UserService_RestoreAccount_FullMethodName, &userPb.RestoreAccountRequest{UserId: "usr_1"}, nil},
+		{"other account deletion", false, false, withToken(t, anonymous, "usr_2"), userPb.UserService_DeleteAccount_FullMethodName, deleteAccount, model.ErrTokenUserMismatch},
+		{"open method without certificate", true, false, anonymous, userPb.UserService_GetProfile_FullMethodName, profile, model.ErrUnknownClient},
+		{"unmapped certificate", true, false, withClientCert("/stranger"), userPb.UserService_GetProfile_FullMethodName, profile, model.ErrUnknownClient},
+		{"open method", true, false, withClientCert("/content"), userPb.UserService_GetProfile_FullMethodName, profile, nil},
+		{"admin export", true, false, withClientCert("/admin"), userPb.UserService_ExportUserData_FullMethodName, nil, nil},
+		{"content export", true, false, withClientCert("/content"), userPb.UserService_ExportUserData_FullMethodName, nil, model.ErrCallerNotAllowed},
+		{"admin delete account", true, false, withClientCert("/admin"), userPb.UserService_DeleteAccount_FullMethodName, deleteAccount, nil},
+		{"content delete account", true, false, withClientCert("/content"), userPb.UserService_DeleteAccount_FullMethodName, deleteAccount, model.ErrCallerNotAllowed},
+		{"gateway own account deletion", true, false, withToken(t, withClientCert("/gateway"), "usr_1"), userPb.UserService_DeleteAccount_FullMethodName, deleteAccount, nil},
+		{"gateway without token", true, false, withClientCert("/gateway"), userPb.UserService_GetProfile_FullMethodName, profile, model.ErrMissingToken},
+		{"gateway ban", true, false, withToken(t, withClientCert("/gateway"), "usr_1"), userPb.UserService_BanUser_FullMethodName, &userPb.BanUserRequest{UserId: "usr_1"}, model.ErrCallerNotAllowed},
+		{"gateway stream", true, false, withToken(t, withClientCert("/gateway"), "usr_1"), userPb.UserService_ExportUserData_FullMethodName, nil, model.ErrCallerNotAllowed},
+		{"content reputation", true, false, withClientCert("/content"), userPb.UserService_RecordReputationEvent_FullMethodName, &userPb.RecordReputationEventRequest{}, nil},
+		{"admin reputation", true, false, withClientCert("/admin"), userPb.UserService_RecordReputationEvent_FullMethodName, &userPb.RecordReputationEventRequest{}, model.ErrCallerNotAllowed},
+		{"health check", true, false, anonymous, "/grpc.health.v1.Health/Check", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAuthorizer(identities, tt.mutualTLS, tt.allowAdmin).authorize(tt.ctx, tt.method, tt.req)
+			if !errors.Is(err, tt.want) || (err != nil) != (tt.want != nil) {
+				t.Errorf("authorize() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditActorOfSelfServiceCalls(t *testing.T) {
+	authorizer := NewAuthorizer(map[string]string{"spiffe://ecommerce/gateway": IdentityGateway}, true, false)
+	ctx, err := authorizer.authorize(withToken(t, withClientCert("/gateway"), "usr_1"),
+		userPb.UserService_DeleteAccount_FullMethodName, &userPb.DeleteAccountRequest{UserId: "usr_1"})
+	if err != nil {
+		t.Fatalf("authorize() = %v", err)
+	}
+	if got := auditActor(ctx); got != "usr_1" {
+		t.Errorf("auditActor() = %q, want the user", got)
+	}
+
+	ctx, err = NewAuthorizer(map[string]string{"spiffe://ecommerce/admin": IdentityAdmin}, true, false).authorize(withClientCert("/admin"),
+		userPb.UserService_DeleteAccount_FullMethodName, &userPb.DeleteAccountRequest{UserId: "usr_1"})
+	if err != nil {
+		t.Fatalf("authorize() = %v", err)
+	}
+	if got := auditActor(ctx); got != IdentityAdmin {
+		t.Errorf("auditActor() = %q, want %q", got, IdentityAdmin)
+	}
+}
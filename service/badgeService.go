@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+)
+
+// ListBadges returns the registered badge definitions
+func (s *UserService) ListBadges(ctx context.Context, req *userPb.ListBadgesRequest) (*userPb.ListBadgesResponse, error) {
+	response := &userPb.ListBadgesResponse{}
+	for _, def := range model.Badges() {
+		if req.Tier != "" && def.Tier != req.Tier {
+			continue
+		}
+		response.Badges = append(response.Badges, toBadgeResponse(def))
+	}
+	return response, nil
+}
+
+// GetUserBadges returns the badges a user has earned with per-tier totals
+func (s *UserService) GetUserBadges(ctx context.Context, req *userPb.GetUserBadgesRequest) (*userPb.GetUserBadgesResponse, error) {
+	if _, err := s.repo.GetUserByID(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	awarded, err := s.repo.GetUserBadges(ctx, req.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user badges: %w", err)
+	}
+
+	response := &userPb.GetUserBadgesResponse{UserId: req.UserId}
+	for _, award := range awarded {
+		def, ok := model.LookupBadge(award.BadgeID)
+		if !ok {
+			// The badge was retired from the registry; keep what was stored
+			def = model.BadgeDefinition{ID: award.BadgeID, Name: award.BadgeID, Tier: award.Tier}
+		}
+		response.Badges = append(response.Badges, &userPb.AwardedBadge{
+			Badge:     toBadgeResponse(def),
+			AwardedAt: award.AwardedAt.Unix(),
+		})
+
+		switch award.Tier {
+		case model.BadgeTierBronze:
+			response.Bronze++
+		case model.BadgeTierSilver:
+			response.Silver++
+		case model.BadgeTierGold:
+			response.Gold++
+		}
+	}
+	return response, nil
+}
+
+// evaluateBadges runs every badge rule against the user's current state and
+// awards the badges they have earned. Awards are idempotent, so it is safe to
+// call after any reputation event or profile change. Failures are logged and
+// never fail the calling RPC.
+func (s *UserService) evaluateBadges(ctx context.Context, userID string) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("badge evaluation skipped for %s: %v", userID, err)
+		return
+	}
+
+	counts, err := s.repo.CountReputationEventsByReason(ctx, userID)
+	if err != nil {
+		log.Printf("badge evaluation skipped for %s: %v", userID, err)
+		return
+	}
+
+	facts := model.BadgeFacts{User: user, ReasonCounts: counts}
+	now := time.Now()
+
+	var earned []*model.UserBadge
+	for _, def := range model.Badges() {
+		if def.Earned != nil && def.Earned(facts) {
+			earned = append(earned, &model.UserBadge{
+				UserID:    userID,
+				BadgeID:   def.ID,
+				Tier:      def.Tier,
+				AwardedAt: now,
+			})
+		}
+	}
+
+	if err := s.repo.AwardBadges(ctx, earned); err != nil {
+		log.Printf("failed to award badges to %s: %v", userID, err)
+	}
+}
+
+func toBadgeResponse(def model.BadgeDefinition) *userPb.Badge {
+	return &userPb.Badge{
+		Id:          def.ID,
+		Name:        def.Name,
+		Description: def.Description,
+		Tier:        def.Tier,
+	}
+}
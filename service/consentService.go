@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	"github.com/liju-github/EcommerceUserService/repository"
+)
+
+// RecordConsent records the user's acceptance of the current version of a policy
+func (s *UserService) RecordConsent(ctx context.Context, req *userPb.RecordConsentRequest) (*userPb.RecordConsentResponse, error) {
+	var consent *model.Consent
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if _, err := tx.GetUserByID(ctx, req.UserId); err != nil {
+			return err
+		}
+
+		var err error
+		consent, err = s.recordConsent(ctx, tx, req.UserId, req.PolicyType, req.Version, model.ConsentSourceAPI)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.RecordConsentResponse{
+		Success: true,
+		Consent: toConsentResponse(consent),
+	}, nil
+}
+
+// WithdrawConsent withdraws the user's active consent to a policy
+func (s *UserService) WithdrawConsent(ctx context.Context, req *userPb.WithdrawConsentRequest) (*userPb.WithdrawConsentResponse, error) {
+	if _, ok := s.policyVersions[req.PolicyType]; !ok {
+		return nil, model.ErrUnknownPolicy
+	}
+
+	if err := s.repo.WithdrawConsents(ctx, req.UserId, req.PolicyType, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &userPb.WithdrawConsentResponse{
+		Success: true,
+		Message: "Consent withdrawn",
+	}, nil
+}
+
+// GetConsents returns the user's consent history and the current policy versions
+func (s *UserService) GetConsents(ctx context.Context, req *userPb.GetConsentsRequest) (*userPb.GetConsentsResponse, error) {
+	consents, err := s.repo.GetConsents(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &userPb.GetConsentsResponse{
+		CurrentTermsVersion:     s.policyVersions[model.PolicyTerms],
+		CurrentPrivacyVersion:   s.policyVersions[model.PolicyPrivacy],
+		CurrentMarketingVersion: s.policyVersions[model.PolicyMarketing],
+	}
+	for _, consent := range consents {
+		response.Consents = append(response.Consents, toConsentResponse(consent))
+	}
+	return response, nil
+}
+
+// recordConsent stores an acceptance of a policy. An empty version means the
+// current one; older versions cannot be accepted.
+func (s *UserService) recordConsent(ctx context.Context, repo repository.UserRepository, userID, policyType, version, source string) (*model.Consent, error) {
+	current, ok := s.policyVersions[policyType]
+	if !ok {
+		return nil, model.ErrUnknownPolicy
+	}
+	if version == "" {
+		version = current
+	}
+	if version != current {
+		return nil, model.ErrStaleConsentVersion
+	}
+
+	consent := &model.Consent{
+		UserID:     userID,
+		PolicyType: policyType,
+		Version:    version,
+		AcceptedAt: time.Now(),
+		SourceIP:   clientIP(ctx),
+		Source:     source,
+	}
+	if err := repo.CreateConsent(ctx, consent); err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// termsAcceptanceRequired reports whether the user still has to accept the
+// current terms of service
+func (s *UserService) termsAcceptanceRequired(ctx context.Context, userID string) (bool, error) {
+	consent, err := s.repo.GetActiveConsent(ctx, userID, model.PolicyTerms)
+	if err != nil {
+		return false, err
+	}
+	return consent == nil || consent.Version != s.policyVersions[model.PolicyTerms], nil
+}
+
+func toConsentResponse(consent *model.Consent) *userPb.Consent {
+	response := &userPb.Consent{
+		Id:         uint64(consent.ID),
+		PolicyType: consent.PolicyType,
+		Version:    consent.Version,
+		AcceptedAt: consent.AcceptedAt.Unix(),
+		SourceIp:   consent.SourceIP,
+		Source:     consent.Source,
+	}
+	if consent.WithdrawnAt != nil {
+		response.WithdrawnAt = consent.WithdrawnAt.Unix()
+	}
+	return response
+}
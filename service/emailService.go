@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	"github.com/liju-github/EcommerceUserService/repository"
+	util "github.com/liju-github/EcommerceUserService/utils"
+)
+
+const (
+	emailChangeCodeDigits      = 6
+	maxEmailChangeAttempts     = 5
+	emailChangeCancelTokenSize = 32
+)
+
+// RequestEmailChange sends a confirmation code to the new address and a
+// notice with a cancel token to the current one. The email only changes once
+// ConfirmEmailChange succeeds.
+func (s *UserService) RequestEmailChange(ctx context.Context, req *userPb.RequestEmailChangeRequest) (*userPb.RequestEmailChangeResponse, error) {
+	var (
+		user        *model.User
+		newEmail    string
+		code        string
+		cancelToken string
+	)
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		var err error
+		user, err = tx.GetUserByID(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		newEmail = model.NormalizeEmail(req.NewEmail)
+		if !validEmail(newEmail) {
+			return &model.FieldError{Field: "newEmail", Err: model.ErrInvalidEmail}
+		}
+		if model.CanonicalEmail(newEmail) == model.CanonicalEmail(user.Email) {
+			return model.ErrEmailUnchanged
+		}
+		// Checked again on confirmation, this only saves a pointless round trip
+		if existing, err := tx.GetUserByEmail(ctx, newEmail); err == nil && existing != nil {
+			return model.ErrDuplicateEmail
+		}
+
+		code, err = util.GenerateOTP(emailChangeCodeDigits)
+		if err != nil {
+			return err
+		}
+		cancelToken, err = util.GenerateSecret(emailChangeCancelTokenSize)
+		if err != nil {
+			return err
+		}
+
+		user.PendingEmail = newEmail
+		user.EmailChangeCodeHash = util.HashOTP(code)
+		user.EmailChangeCancelHash = util.HashOTP(cancelToken)
+		user.EmailChangeExpiresAt = time.Now().Add(s.emailChangeTTL)
+		user.EmailChangeAttempts = 0
+		return tx.UpdateEmailChange(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mailer.SendEmail(ctx, newEmail, "Confirm your new email address",
+		fmt.Sprintf("Your confirmation code is %s. It expires at %s.", code, user.EmailChangeExpiresAt.UTC().Format(time.RFC1123))); err != nil {
+		return nil, fmt.Errorf("failed to send confirmation code: %w", err)
+	}
+	if err := s.mailer.SendEmail(ctx, user.Email, "Your email address is being changed",
+		fmt.Sprintf("A request was made to change your account email to %s.\nIf this was not you, cancel it with this token: %s", newEmail, cancelToken)); err != nil {
+		return nil, fmt.Errorf("failed to send email change notice: %w", err)
+	}
+
+	return &userPb.RequestEmailChangeResponse{
+		Success:   true,
+		Message:   "Confirmation code sent to the new email address",
+		ExpiresAt: user.EmailChangeExpiresAt.Unix(),
+	}, nil
+}
+
+// ConfirmEmailChange applies a pending email change and reissues the token
+// with the new email claim
+func (s *UserService) ConfirmEmailChange(ctx context.Context, req *userPb.ConfirmEmailChangeRequest) (*userPb.ConfirmEmailChangeResponse, error) {
+	// A wrong code still has to commit the failed attempt, so it is reported
+	// after the transaction instead of rolling it back
+	var (
+		user      *model.User
+		verifyErr error
+	)
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		var err error
+		verifyErr = nil
+		user, err = tx.GetUserByID(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		if user.PendingEmail == "" || user.EmailChangeCodeHash == "" {
+			return model.ErrNoPendingEmailChange
+		}
+		if time.Now().After(user.EmailChangeExpiresAt) {
+			return model.ErrCodeExpired
+		}
+		if user.EmailChangeAttempts >= maxEmailChangeAttempts {
+			return model.ErrTooManyAttempts
+		}
+
+		if !util.CheckOTP(req.Code, user.EmailChangeCodeHash) {
+			user.EmailChangeAttempts++
+			if err := tx.UpdateEmailChange(ctx, user); err != nil {
+				return err
+			}
+			verifyErr = model.ErrInvalidCode
+			return nil
+		}
+
+		if existing, err := tx.GetUserByEmail(ctx, user.PendingEmail); err == nil && existing != nil && existing.ID != user.ID {
+			return model.ErrDuplicateEmail
+		}
+
+		user.Email = user.PendingEmail
+		clearEmailChange(user)
+		return tx.UpdateEmailChange(ctx, user)
+	})
+	if err == nil {
+		err = verifyErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := util.GenerateToken(user, RoleUser, model.PrivilegesFor(s.privilegeTiers, user.Reputation))
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.ConfirmEmailChangeResponse{
+		Success: true,
+		Message: "Email address changed successfully",
+		Email:   user.Email,
+		Token:   token,
+	}, nil
+}
+
+// CancelEmailChange discards a pending email change using the token sent to
+// the old address
+func (s *UserService) CancelEmailChange(ctx context.Context, req *userPb.CancelEmailChangeRequest) (*userPb.CancelEmailChangeResponse, error) {
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		user, err := tx.GetUserByID(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		if user.PendingEmail == "" || user.EmailChangeCancelHash == "" {
+			return model.ErrNoPendingEmailChange
+		}
+		if !util.CheckOTP(req.CancelToken, user.EmailChangeCancelHash) {
+			return model.ErrInvalidCancelToken
+		}
+
+		clearEmailChange(user)
+		return tx.UpdateEmailChange(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.CancelEmailChangeResponse{
+		Success: true,
+		Message: "Email change cancelled",
+	}, nil
+}
+
+func clearEmailChange(user *model.User) {
+	user.PendingEmail = ""
+	user.EmailChangeCodeHash = ""
+	user.EmailChangeCancelHash = ""
+	user.EmailChangeExpiresAt = time.Time{}
+	user.EmailChangeAttempts = 0
+}
+
+// validEmail accepts a bare address, the same form Register stores
+func validEmail(email string) bool {
+	email = model.NormalizeEmail(email)
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
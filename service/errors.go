@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"github.com/liju-github/EcommerceUserService/postal"
+)
+
+// errorDomain is the ErrorInfo domain of errors raised by this service
+const errorDomain = "user.ecommerce"
+
+// errorLocale is the locale of the LocalizedMessage attached to errors
+const errorLocale = "en-US"
+
+// errorMapping is the gRPC code and machine readable reason an error is
+// reported with
+type errorMapping struct {
+	err    error
+	code   codes.Code
+	reason string
+}
+
+// errorMappings lists the errors clients can act on. Anything else is an
+// internal error whose details are only logged.
+var errorMappings = []errorMapping{
+	{model.ErrUserNotFound, codes.NotFound, "USER_NOT_FOUND"},
+	{model.ErrAddressNotFound, codes.NotFound, "ADDRESS_NOT_FOUND"},
+	{postal.ErrPincodeNotFound, codes.NotFound, "PINCODE_NOT_FOUND"},
+
+	{model.ErrDuplicateEmail, codes.AlreadyExists, "EMAIL_TAKEN"},
+	{model.ErrDuplicatePhone, codes.AlreadyExists, "PHONE_TAKEN"},
+
+	{model.ErrInvalidPassword, codes.Unauthenticated, "INVALID_CREDENTIALS"},
+	{model.ErrInvalidToken, codes.Unauthenticated, "INVALID_TOKEN"},
+	{model.ErrMissingToken, codes.Unauthenticated, "MISSING_TOKEN"},
+
+	{model.ErrUserNotVerified, codes.PermissionDenied, "USER_NOT_VERIFIED"},
+	{model.ErrUnknownClient, codes.Unauthenticated, "UNKNOWN_CLIENT"},
+	{model.ErrCallerNotAllowed, codes.PermissionDenied, "CALLER_NOT_ALLOWED"},
+	{model.ErrTokenUserMismatch, codes.PermissionDenied, "TOKEN_USER_MISMATCH"},
+
+	{model.ErrInvalidRequest, codes.InvalidArgument, "INVALID_REQUEST"},
+	{model.ErrRequiredField, codes.InvalidArgument, "REQUIRED_FIELD"},
+	{model.ErrImmutableField, codes.InvalidArgument, "IMMUTABLE_FIELD"},
+	{model.ErrUnknownField, codes.InvalidArgument, "UNKNOWN_FIELD"},
+	{model.ErrInvalidCode, codes.InvalidArgument, "INVALID_CODE"},
+	{model.ErrInvalidCancelToken, codes.InvalidArgument, "INVALID_CANCEL_TOKEN"},
+	{model.ErrInvalidEmail, codes.InvalidArgument, "INVALID_EMAIL"},
+	{model.ErrEmailUnchanged, codes.InvalidArgument, "EMAIL_UNCHANGED"},
+	{model.ErrInvalidPhoneNumber, codes.InvalidArgument, "INVALID_PHONE_NUMBER"},
+	{model.ErrInvalidLeaderboardPeriod, codes.InvalidArgument, "INVALID_LEADERBOARD_PERIOD"},
+	{model.ErrInvalidPageToken, codes.InvalidArgument, "INVALID_PAGE_TOKEN"},
+	{model.ErrInvalidAddressUsage, codes.InvalidArgument, "INVALID_ADDRESS_USAGE"},
+	{model.ErrUnknownPolicy, codes.InvalidArgument, "UNKNOWN_POLICY"},
+	{model.ErrStaleConsentVersion, codes.InvalidArgument, "STALE_CONSENT_VERSION"},
+	{postal.ErrInvalidPincode, codes.InvalidArgument, "INVALID_PINCODE"},
+	{postal.ErrUnknownState, codes.InvalidArgument, "UNKNOWN_STATE"},
+	{postal.ErrPincodeMismatch, codes.InvalidArgument, "PINCODE_STATE_MISMATCH"},
+
+	{model.ErrTermsNotAccepted, codes.FailedPrecondition, "TERMS_NOT_ACCEPTED"},
+	{model.ErrNoActiveConsent, codes.FailedPrecondition, "NO_ACTIVE_CONSENT"},
+	{model.ErrAddressLimitReached, codes.FailedPrecondition, "ADDRESS_LIMIT_REACHED"},
+	{model.ErrNoPhoneNumber, codes.FailedPrecondition, "NO_PHONE_NUMBER"},
+	{model.ErrCodeExpired, codes.FailedPrecondition, "CODE_EXPIRED"},
+	{model.ErrTooManyAttempts, codes.FailedPrecondition, "TOO_MANY_ATTEMPTS"},
+	{model.ErrNoPendingEmailChange, codes.FailedPrecondition, "NO_PENDING_EMAIL_CHANGE"},
+	{model.ErrAccountNotDeleted, codes.FailedPrecondition, "ACCOUNT_NOT_DELETED"},
+	{model.ErrRestoreWindowExpired, codes.FailedPrecondition, "RESTORE_WINDOW_EXPIRED"},
+	{model.ErrAlreadyBanned, codes.FailedPrecondition, "ALREADY_BANNED"},
+
+	{model.ErrVersionConflict, codes.Aborted, "VERSION_CONFLICT"},
+}
+
+// UnaryErrorInterceptor converts errors returned by the service into gRPC
+// statuses
+func UnaryErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatus(ctx, err)
+}
+
+// StreamErrorInterceptor is UnaryErrorInterceptor for streaming RPCs
+func StreamErrorInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatus(ss.Context(), handler(srv, ss))
+}
+
+// toStatus maps an error to a gRPC status. Errors listed in errorMappings
+// get their code with ErrorInfo, LocalizedMessage and, for field and
+// validation errors, BadRequest details. Queries aborted because the call was
+// cancelled or ran out of time report Canceled or DeadlineExceeded, and
+// anything else is Internal.
+func toStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if mapping, ok := mappingFor(err); ok {
+		return detailedStatus(mapping, err).Err()
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	// Not every driver wraps the context error, so also check the call itself
+	switch ctx.Err() {
+	case context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	log.Printf("internal error: %v", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// mappingFor finds the entry of errorMappings err matches
+func mappingFor(err error) (errorMapping, bool) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping, true
+		}
+	}
+	return errorMapping{}, false
+}
+
+// detailedStatus builds the status of an error found in errorMappings
+func detailedStatus(mapping errorMapping, err error) *status.Status {
+	st := status.New(mapping.code, err.Error())
+	details := []protoadapt.MessageV1{
+		&errdetails.ErrorInfo{Reason: mapping.reason, Domain: errorDomain},
+		&errdetails.LocalizedMessage{Locale: errorLocale, Message: mapping.err.Error()},
+	}
+
+	if violations := fieldViolations(err); len(violations) > 0 {
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	detailed, detailErr := st.WithDetails(details...)
+	if detailErr != nil {
+		return st
+	}
+	return detailed
+}
+
+// fieldViolations lists the request fields err blames, either every field
+// of a ValidationError or the single field of a FieldError
+func fieldViolations(err error) []*errdetails.BadRequest_FieldViolation {
+	var fieldErrs []*model.FieldError
+	var validationErr *model.ValidationError
+	var fieldErr *model.FieldError
+	switch {
+	case errors.As(err, &validationErr):
+		fieldErrs = validationErr.Violations
+	case errors.As(err, &fieldErr):
+		fieldErrs = []*model.FieldError{fieldErr}
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		violations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       fieldErr.Field,
+			Description: fieldErr.Err.Error(),
+		}
+	}
+	return violations
+}
+
+// versionConflict reports a stale expected version as Aborted, with the
+// current profile attached so the client can merge and retry
+func (s *UserService) versionConflict(ctx context.Context, userID string) error {
+	mapping, _ := mappingFor(model.ErrVersionConflict)
+	st := detailedStatus(mapping, model.ErrVersionConflict)
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return st.Err()
+	}
+	if detailed, err := st.WithDetails(toProfileResponse(user)); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	util "github.com/liju-github/EcommerceUserService/utils"
+)
+
+const exportChunkSize = 64 * 1024
+
+// exportProfile is the part of model.User included in a data export. Password
+// hashes and verification secrets are deliberately left out.
+type exportProfile struct {
+	ID              string
+	Email           string
+	Name            string
+	StreetName      string
+	Locality        string
+	State           string
+	Pincode         string
+	PhoneNumber     string
+	IsPhoneVerified bool
+	IsVerified      bool
+	IsBanned        bool
+	Reputation      int32
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// exportSession is a login token that has not expired yet. Tokens are not
+// stored, so sessions are derived from the successful logins that issued them.
+type exportSession struct {
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	IPAddress string
+	UserAgent string
+}
+
+// exportFile is a JSON file of the export archive
+type exportFile struct {
+	name string
+	data interface{}
+}
+
+// ExportUserData streams a zip archive holding every record kept about the
+// user as JSON. The archive is compressed while it is sent, so it is never
+// held in memory as a whole. Each export is recorded in the audit log.
+func (s *UserService) ExportUserData(req *userPb.ExportUserDataRequest, stream grpc.ServerStreamingServer[userPb.ExportUserDataChunk]) error {
+	ctx := stream.Context()
+	files, err := s.collectExport(ctx, req.UserId)
+	if err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, s.repo, auditActor(ctx), model.AuditActionDataExport, req.UserId, map[string]interface{}{
+		"clientIp": clientIP(ctx),
+	}); err != nil {
+		return fmt.Errorf("failed to record data export: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeExport(pw, req.UserId, files))
+	}()
+	// Unblocks the writer when sending fails
+	defer pr.Close()
+
+	fileName := fmt.Sprintf("%s-export-%s.zip", req.UserId, time.Now().UTC().Format("20060102T150405Z"))
+	buf := make([]byte, exportChunkSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(pr, buf)
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return err
+		}
+
+		chunk := &userPb.ExportUserDataChunk{
+			Data:   buf[:n],
+			Offset: offset,
+		}
+		if offset == 0 {
+			chunk.FileName = fileName
+		}
+		offset += int64(n)
+		if last {
+			chunk.TotalSize = offset
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// collectExport reads the user's records into the files of the export
+func (s *UserService) collectExport(ctx context.Context, userID string) ([]exportFile, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := s.repo.ListAddresses(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	badges, err := s.repo.GetUserBadges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	banHistory, err := s.repo.GetAuditLogs(ctx, userID, model.AuditActionBan, model.AuditActionUnban)
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := s.repo.GetReputationEvents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	logins, err := s.repo.GetLoginEvents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	consents, err := s.repo.GetConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := []exportSession{}
+	for _, login := range logins {
+		expiresAt := login.CreatedAt.Add(util.TokenExpiry)
+		if login.Success && expiresAt.After(now) {
+			sessions = append(sessions, exportSession{
+				IssuedAt:  login.CreatedAt,
+				ExpiresAt: expiresAt,
+				IPAddress: login.IPAddress,
+				UserAgent: login.UserAgent,
+			})
+		}
+	}
+
+	return []exportFile{
+		{"profile.json", exportProfile{
+			ID:              user.ID,
+			Email:           user.Email,
+			Name:            user.Name,
+			StreetName:      user.StreetName,
+			Locality:        user.Locality,
+			State:           user.State,
+			Pincode:         user.Pincode,
+			PhoneNumber:     user.PhoneNumber,
+			IsPhoneVerified: user.IsPhoneVerified,
+			IsVerified:      user.IsVerified,
+			IsBanned:        user.IsBanned,
+			Reputation:      user.Reputation,
+			CreatedAt:       user.CreatedAt,
+			UpdatedAt:       user.UpdatedAt,
+		}},
+		{"addresses.json", addresses},
+		{"badges.json", badges},
+		{"ban_history.json", banHistory},
+		{"reputation_ledger.json", ledger},
+		{"login_history.json", logins},
+		{"sessions.json", sessions},
+		{"consents.json", consents},
+	}, nil
+}
+
+// writeExport writes the files and a manifest listing them as a zip archive
+func writeExport(w io.Writer, userID string, files []exportFile) error {
+	zw := zip.NewWriter(w)
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if err := writeJSONFile(zw, file.name, file.data); err != nil {
+			return err
+		}
+		names = append(names, file.name)
+	}
+	if err := writeJSONFile(zw, "manifest.json", map[string]interface{}{
+		"userId":      userID,
+		"generatedAt": time.Now().UTC(),
+		"files":       names,
+	}); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish export archive: %w", err)
+	}
+	return nil
+}
+
+func writeJSONFile(zw *zip.Writer, name string, data interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export: %w", name, err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	"github.com/liju-github/EcommerceUserService/repository"
+	util "github.com/liju-github/EcommerceUserService/utils"
+)
+
+const (
+	phoneOTPDigits      = 6
+	maxPhoneOTPAttempts = 5
+)
+
+// SendPhoneOTP texts a one-time code to the user's phone number, or to a new
+// number that replaces it once verified
+func (s *UserService) SendPhoneOTP(ctx context.Context, req *userPb.SendPhoneOTPRequest) (*userPb.SendPhoneOTPResponse, error) {
+	var (
+		user        *model.User
+		phoneNumber string
+		code        string
+	)
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		var err error
+		user, err = tx.GetUserByID(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		phoneNumber = user.PhoneNumber
+		if req.PhoneNumber != "" {
+			phoneNumber = req.PhoneNumber
+		}
+		if phoneNumber == "" {
+			return model.ErrNoPhoneNumber
+		}
+		if phoneNumber, err = util.NormalizePhone(phoneNumber, s.phoneRegion); err != nil {
+			return &model.FieldError{Field: "phoneNumber", Err: err}
+		}
+
+		taken, err := tx.IsPhoneVerifiedByOtherUser(ctx, phoneNumber, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return model.ErrDuplicatePhone
+		}
+
+		code, err = util.GenerateOTP(phoneOTPDigits)
+		if err != nil {
+			return err
+		}
+
+		user.PendingPhoneNumber = phoneNumber
+		user.PhoneOTPHash = util.HashOTP(code)
+		user.PhoneOTPExpiresAt = time.Now().Add(s.phoneOTPTTL)
+		user.PhoneOTPAttempts = 0
+		return tx.UpdatePhoneVerification(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(s.phoneOTPTTL.Minutes()))
+	if err := s.sms.SendSMS(ctx, phoneNumber, message); err != nil {
+		return nil, fmt.Errorf("failed to send verification code: %w", err)
+	}
+
+	return &userPb.SendPhoneOTPResponse{
+		Success:     true,
+		Message:     "Verification code sent",
+		PhoneNumber: phoneNumber,
+		ExpiresAt:   user.PhoneOTPExpiresAt.Unix(),
+	}, nil
+}
+
+// VerifyPhoneOTP confirms the code sent by SendPhoneOTP and marks the phone
+// number as verified
+func (s *UserService) VerifyPhoneOTP(ctx context.Context, req *userPb.VerifyPhoneOTPRequest) (*userPb.VerifyPhoneOTPResponse, error) {
+	// A wrong code still has to commit the failed attempt, so it is reported
+	// after the transaction instead of rolling it back
+	var (
+		user      *model.User
+		verifyErr error
+	)
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		var err error
+		verifyErr = nil
+		user, err = tx.GetUserByID(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		if user.PhoneOTPHash == "" || user.PendingPhoneNumber == "" {
+			return model.ErrInvalidCode
+		}
+		if time.Now().After(user.PhoneOTPExpiresAt) {
+			return model.ErrCodeExpired
+		}
+		if user.PhoneOTPAttempts >= maxPhoneOTPAttempts {
+			return model.ErrTooManyAttempts
+		}
+
+		if !util.CheckOTP(req.Code, user.PhoneOTPHash) {
+			user.PhoneOTPAttempts++
+			if err := tx.UpdatePhoneVerification(ctx, user); err != nil {
+				return err
+			}
+			verifyErr = model.ErrInvalidCode
+			return nil
+		}
+
+		// Another account may have verified the number since the code was sent
+		taken, err := tx.IsPhoneVerifiedByOtherUser(ctx, user.PendingPhoneNumber, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return model.ErrDuplicatePhone
+		}
+
+		user.PhoneNumber = user.PendingPhoneNumber
+		user.IsPhoneVerified = true
+		user.PendingPhoneNumber = ""
+		user.PhoneOTPHash = ""
+		user.PhoneOTPExpiresAt = time.Time{}
+		user.PhoneOTPAttempts = 0
+		return tx.UpdatePhoneVerification(ctx, user)
+	})
+	if err == nil {
+		err = verifyErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.evaluateBadges(ctx, user.ID)
+
+	return &userPb.VerifyPhoneOTPResponse{
+		Success:     true,
+		Message:     "Phone number successfully verified",
+		PhoneNumber: user.PhoneNumber,
+	}, nil
+}
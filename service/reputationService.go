@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	"github.com/liju-github/EcommerceUserService/postal"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+	"github.com/liju-github/EcommerceUserService/repository"
+)
+
+const (
+	defaultLeaderboardPageSize = 20
+	maxLeaderboardPageSize     = 100
+)
+
+// RecordReputationEvent appends a reputation change to the user's ledger
+func (s *UserService) RecordReputationEvent(ctx context.Context, req *userPb.RecordReputationEventRequest) (*userPb.RecordReputationEventResponse, error) {
+	event := model.ReputationEvent{
+		UserID:   req.UserId,
+		Delta:    req.Delta,
+		Reason:   req.Reason,
+		SourceID: req.SourceId,
+	}
+	var user *model.User
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if err := tx.AddReputationEvent(ctx, &event); err != nil {
+			return err
+		}
+
+		var err error
+		if user, err = tx.GetUserByID(ctx, req.UserId); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.evaluateBadges(ctx, req.UserId)
+
+	return &userPb.RecordReputationEventResponse{
+		Success:    true,
+		Reputation: user.Reputation,
+	}, nil
+}
+
+// GetLeaderboard returns a page of top contributors for a period, optionally
+// narrowed to a state or locality
+func (s *UserService) GetLeaderboard(ctx context.Context, req *userPb.GetLeaderboardRequest) (*userPb.GetLeaderboardResponse, error) {
+	period := req.Period
+	if period == "" {
+		period = model.LeaderboardAllTime
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultLeaderboardPageSize
+	}
+	if pageSize > maxLeaderboardPageSize {
+		pageSize = maxLeaderboardPageSize
+	}
+
+	state := req.State
+	if state != "" {
+		code, err := postal.NormalizeState(state)
+		if err != nil {
+			return nil, err
+		}
+		state = code
+	}
+
+	offset := 0
+	if req.PageToken != "" {
+		parsed, err := strconv.Atoi(req.PageToken)
+		if err != nil || parsed < 0 {
+			return nil, model.ErrInvalidPageToken
+		}
+		offset = parsed
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	entries, err := s.repo.GetLeaderboard(ctx, model.LeaderboardFilter{
+		Period:   period,
+		State:    state,
+		Locality: req.Locality,
+		Offset:   offset,
+		Limit:    pageSize + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+
+	response := &userPb.GetLeaderboardResponse{}
+	if len(entries) > pageSize {
+		entries = entries[:pageSize]
+		response.NextPageToken = strconv.Itoa(offset + pageSize)
+	}
+
+	for i, entry := range entries {
+		response.Entries = append(response.Entries, &userPb.LeaderboardEntry{
+			Rank:     int32(offset + i + 1),
+			UserId:   entry.UserID,
+			Name:     entry.Name,
+			Points:   entry.Points,
+			State:    entry.State,
+			Locality: entry.Locality,
+		})
+		if refreshedAt := entry.RefreshedAt.Unix(); refreshedAt > response.RefreshedAt {
+			response.RefreshedAt = refreshedAt
+		}
+	}
+
+	return response, nil
+}
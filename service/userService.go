@@ -4,12 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
+	config "github.com/liju-github/EcommerceUserService/configs"
 	model "github.com/liju-github/EcommerceUserService/models"
+	"github.com/liju-github/EcommerceUserService/notify"
 	userPb "github.com/liju-github/EcommerceUserService/proto/user"
 	"github.com/liju-github/EcommerceUserService/repository"
 	util "github.com/liju-github/EcommerceUserService/utils"
@@ -17,26 +25,54 @@ import (
 
 const (
 	TokenExpiry = 24 * time.Hour
+	RoleUser    = "user"
+
+	userIDPrefix = "usr_"
 )
 
 type UserService struct {
 	userPb.UnimplementedUserServiceServer
-	repo repository.UserRepository
+	repo           repository.UserRepository
+	privilegeTiers []model.PrivilegeTier
+	sms            notify.SMSSender
+	phoneRegion    string
+	phoneOTPTTL    time.Duration
+	mailer         notify.Mailer
+	emailChangeTTL time.Duration
+	deletionGrace  time.Duration
+	policyVersions map[string]string
+	ids            util.IDGenerator
 }
 
 type CustomClaims struct {
-	UserID     string `json:"userId"`
-	Email      string `json:"email"`
-	Role       string `json:"role"`
-	Reputation int32  `json:"reputation"`
+	UserID     string   `json:"userId"`
+	Email      string   `json:"email"`
+	Role       string   `json:"role"`
+	Reputation int32    `json:"reputation"`
+	Privileges []string `json:"privileges"`
 	jwt.RegisteredClaims
 }
 
-func NewUserService(repo repository.UserRepository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo repository.UserRepository, cfg config.Config) *UserService {
+	return &UserService{
+		repo:           repo,
+		privilegeTiers: cfg.PrivilegeTiers,
+		sms:            notify.NewSMSSender(cfg.SMSOutboxPath),
+		phoneRegion:    cfg.PhoneDefaultRegion,
+		phoneOTPTTL:    cfg.PhoneOTPTTL,
+		mailer:         notify.NewMailer(cfg.MailOutboxPath),
+		emailChangeTTL: cfg.EmailChangeTTL,
+		deletionGrace:  cfg.AccountDeletionGracePeriod,
+		policyVersions: map[string]string{
+			model.PolicyTerms:     cfg.TermsVersion,
+			model.PolicyPrivacy:   cfg.PrivacyVersion,
+			model.PolicyMarketing: cfg.MarketingVersion,
+		},
+		ids: cfg.IDGenerator,
+	}
 }
 func (s *UserService) GetAllUsers(ctx context.Context, req *userPb.GetAllUsersRequest) (*userPb.GetAllUsersResponse, error) {
-	users, err := s.repo.GetAllUsers()
+	users, err := s.repo.GetAllUsers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve users: %w", err)
 	}
@@ -44,17 +80,20 @@ func (s *UserService) GetAllUsers(ctx context.Context, req *userPb.GetAllUsersRe
 	var userResponses []*userPb.User
 	for _, user := range users {
 		userResponses = append(userResponses, &userPb.User{
-			Id:          user.ID,
-			Email:       user.Email,
-			Name:        user.Name,
-			Reputation:  user.Reputation,
-			StreetName:  user.StreetName,
-			Locality:    user.Locality,
-			State:       user.State,
-			Pincode:     user.Pincode,
-			PhoneNumber: user.PhoneNumber,
-			IsVerified:  user.IsVerified,
-			IsBanned:    user.IsBanned,
+			Id:              user.ID,
+			Email:           user.Email,
+			Name:            user.Name,
+			Reputation:      user.Reputation,
+			StreetName:      user.StreetName,
+			Locality:        user.Locality,
+			State:           user.State,
+			Pincode:         user.Pincode,
+			PhoneNumber:     user.PhoneNumber,
+			IsVerified:      user.IsVerified,
+			IsBanned:        user.IsBanned,
+			IsPhoneVerified: user.IsPhoneVerified,
+			CreatedAt:       user.CreatedAt.Unix(),
+			UpdatedAt:       user.UpdatedAt.Unix(),
 		})
 	}
 
@@ -65,9 +104,23 @@ func (s *UserService) GetAllUsers(ctx context.Context, req *userPb.GetAllUsersRe
 }
 
 func (s *UserService) Register(ctx context.Context, req *userPb.RegisterRequest) (*userPb.RegisterResponse, error) {
-	existingUser, err := s.repo.GetUserByEmail(req.Email)
-	if err == nil && existingUser != nil {
-		return nil, model.ErrDuplicateEmail
+	if req.AcceptedTermsVersion != s.policyVersions[model.PolicyTerms] {
+		return nil, model.ErrTermsNotAccepted
+	}
+	if req.AcceptedPrivacyVersion != "" && req.AcceptedPrivacyVersion != s.policyVersions[model.PolicyPrivacy] {
+		return nil, model.ErrStaleConsentVersion
+	}
+
+	state, err := normalizeAddress(req.Pincode, req.State)
+	if err != nil {
+		return nil, err
+	}
+
+	phoneNumber := req.PhoneNumber
+	if phoneNumber != "" {
+		if phoneNumber, err = util.NormalizePhone(phoneNumber, s.phoneRegion); err != nil {
+			return nil, &model.FieldError{Field: "phoneNumber", Err: err}
+		}
 	}
 
 	// Generate password hash
@@ -80,22 +133,48 @@ func (s *UserService) Register(ctx context.Context, req *userPb.RegisterRequest)
 	verificationCode := fmt.Sprintf("%06d", time.Now().UnixNano()%1000000)
 
 	user := model.User{
-		ID:               fmt.Sprintf("usr_%d", time.Now().UnixNano()),
-		Email:            req.Email,
+		ID:               s.ids.NewID(userIDPrefix),
+		Email:            model.NormalizeEmail(req.Email),
 		PasswordHash:     string(passwordHash),
 		Name:             req.Name,
 		StreetName:       req.StreetName,
 		Locality:         req.Locality,
-		State:            req.State,
+		State:            state,
 		Pincode:          req.Pincode,
-		PhoneNumber:      req.PhoneNumber,
+		PhoneNumber:      phoneNumber,
 		Reputation:       0,
 		IsVerified:       false,
 		VerificationCode: verificationCode,
+		Version:          1,
 	}
 
-	if err := s.repo.CreateUser(&user); err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+	err = s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		existingUser, err := tx.GetUserByEmail(ctx, req.Email)
+		if err == nil && existingUser != nil {
+			return model.ErrDuplicateEmail
+		}
+
+		if err := tx.CreateUser(ctx, &user); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		if _, err := s.recordConsent(ctx, tx, user.ID, model.PolicyTerms, req.AcceptedTermsVersion, model.ConsentSourceRegister); err != nil {
+			return err
+		}
+		if req.AcceptedPrivacyVersion != "" {
+			if _, err := s.recordConsent(ctx, tx, user.ID, model.PolicyPrivacy, req.AcceptedPrivacyVersion, model.ConsentSourceRegister); err != nil {
+				return err
+			}
+		}
+		if req.MarketingOptIn {
+			if _, err := s.recordConsent(ctx, tx, user.ID, model.PolicyMarketing, "", model.ConsentSourceRegister); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Here you would typically send an email with the verification code
@@ -107,38 +186,85 @@ func (s *UserService) Register(ctx context.Context, req *userPb.RegisterRequest)
 	}, nil
 }
 
-// Login verifies credentials and returns a token
+// dummyPasswordHash is checked against when the email is unknown, so the
+// response takes as long as for a wrong password
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// Login verifies credentials and returns a token. Unknown emails fail like
+// wrong passwords so they do not reveal which addresses have an account.
 func (s *UserService) Login(ctx context.Context, req *userPb.LoginRequest) (*userPb.LoginResponse, error) {
-	user, err := s.repo.GetUserByEmail(req.Email)
+	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if errors.Is(err, model.ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		return nil, model.ErrInvalidPassword
+	}
 	if err != nil {
-		return nil, model.ErrUserNotFound
+		return nil, err
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+	passwordErr := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+	if err := s.repo.CreateLoginEvent(ctx, &model.LoginEvent{
+		UserID:    user.ID,
+		Success:   passwordErr == nil,
+		IPAddress: clientIP(ctx),
+		UserAgent: userAgent(ctx),
+	}); err != nil {
+		log.Printf("failed to record login for %s: %v", user.ID, err)
+	}
+	if passwordErr != nil {
 		return nil, model.ErrInvalidPassword
 	}
 
+	token, err := util.GenerateToken(user, RoleUser, model.PrivilegesFor(s.privilegeTiers, user.Reputation))
+	if err != nil {
+		return nil, err
+	}
+
+	termsRequired, err := s.termsAcceptanceRequired(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &userPb.LoginResponse{
 		Success: true,
+		Token:   token,
 		UserId:  user.ID,
+
+		TermsAcceptanceRequired: termsRequired,
+		CurrentTermsVersion:     s.policyVersions[model.PolicyTerms],
 	}, nil
 }
 
 // VerifyEmail handles email verification
 func (s *UserService) VerifyEmail(ctx context.Context, req *userPb.EmailVerificationRequest) (*userPb.EmailVerificationResponse, error) {
-	user, err := s.repo.GetUserByEmail(req.UserId)
+	var user *model.User
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		var err error
+		user, err = tx.GetUserByEmail(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		if user.VerificationCode != req.VerificationCode {
+			return model.ErrInvalidCode
+		}
+
+		if err := tx.UpdateUserVerification(ctx, user.ID, true); err != nil {
+			return fmt.Errorf("failed to update verification status: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, model.ErrUserNotFound
-	}
-
-	if user.VerificationCode != req.VerificationCode {
-		return nil, model.ErrInvalidCode
-	}
-
-	if err := s.repo.UpdateUserVerification(user.ID, true); err != nil {
-		return nil, fmt.Errorf("failed to update verification status: %w", err)
+		return nil, err
 	}
+	s.evaluateBadges(ctx, user.ID)
 
 	return &userPb.EmailVerificationResponse{
 		Success: true,
@@ -148,22 +274,25 @@ func (s *UserService) VerifyEmail(ctx context.Context, req *userPb.EmailVerifica
 
 // GetProfile retrieves user profile
 func (s *UserService) GetProfile(ctx context.Context, req *userPb.ProfileRequest) (*userPb.ProfileResponse, error) {
-	user, err := s.repo.GetUserProfile(req.UserId)
+	user, err := s.repo.GetUserProfile(ctx, req.UserId)
 	if err != nil {
-		return nil, model.ErrUserNotFound
+		return nil, err
 	}
 
 	return &userPb.ProfileResponse{
-		UserId:      user.ID,
-		Email:       user.Email,
-		Name:        user.Name,
-		Reputation:  user.Reputation,
-		StreetName:  user.StreetName,
-		Locality:    user.Locality,
-		State:       user.State,
-		Pincode:     user.Pincode,
-		PhoneNumber: user.PhoneNumber,
-		IsVerified:  user.IsVerified,
+		UserId:          user.ID,
+		Email:           user.Email,
+		Name:            user.Name,
+		Reputation:      user.Reputation,
+		StreetName:      user.StreetName,
+		Locality:        user.Locality,
+		State:           user.State,
+		Pincode:         user.Pincode,
+		PhoneNumber:     user.PhoneNumber,
+		IsVerified:      user.IsVerified,
+		IsPhoneVerified: user.IsPhoneVerified,
+		Version:         user.Version,
+		Etag:            profileETag(user.Version),
 	}, nil
 }
 
@@ -174,28 +303,29 @@ func (s *UserService) GetUserByToken(ctx context.Context, req *userPb.GetUserByT
 		return nil, model.ErrInvalidToken
 	}
 
-	user, err := s.repo.GetUserByID(claims.UserID)
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
 	if err != nil {
-		return nil, model.ErrUserNotFound
+		return nil, err
 	}
 
-	fmt.Println("user record is ", user)
-
 	if !user.IsVerified {
 		return nil, model.ErrUserNotVerified
 	}
 	response := &userPb.ProfileResponse{
-		UserId:      user.ID,
-		Email:       user.Email,
-		Name:        user.Name,
-		Reputation:  user.Reputation,
-		StreetName:  user.StreetName,
-		Locality:    user.Locality,
-		State:       user.State,
-		Pincode:     user.Pincode,
-		PhoneNumber: user.PhoneNumber,
-		IsVerified:  user.IsVerified,
-		IsBanned:    false,
+		UserId:          user.ID,
+		Email:           user.Email,
+		Name:            user.Name,
+		Reputation:      user.Reputation,
+		StreetName:      user.StreetName,
+		Locality:        user.Locality,
+		State:           user.State,
+		Pincode:         user.Pincode,
+		PhoneNumber:     user.PhoneNumber,
+		IsVerified:      user.IsVerified,
+		IsBanned:        false,
+		IsPhoneVerified: user.IsPhoneVerified,
+		Version:         user.Version,
+		Etag:            profileETag(user.Version),
 	}
 
 	return response, nil
@@ -203,70 +333,164 @@ func (s *UserService) GetUserByToken(ctx context.Context, req *userPb.GetUserByT
 
 // UpdateProfile updates user profile information
 func (s *UserService) UpdateProfile(ctx context.Context, req *userPb.UpdateProfileRequest) (*userPb.UpdateProfileResponse, error) {
-	// Verify token first
-	fmt.Println("the request is ", req)
-
-	// Fetch user by ID
-	user, err := s.repo.GetUserByID(req.UserId)
+	fields, err := profileUpdateFields(req)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("the profile is ", req)
-
-	// Update user fields if new values are provided
-	if req.Name != "" {
-		user.Name = req.Name
-	}
-	if req.StreetName != "" {
-		user.StreetName = req.StreetName
-	}
-	if req.Locality != "" {
-		user.Locality = req.Locality
-	}
-	if req.State != "" {
-		user.State = req.State
-	}
-	if req.Pincode != "" {
-		user.Pincode = req.Pincode
-	}
-	if req.PhoneNumber != "" {
-		user.PhoneNumber = req.PhoneNumber
-	}
 
-	// Save updated user profile in repository
-	if err := s.repo.UpdateUser(user); err != nil {
-		return nil, fmt.Errorf("failed to update profile: %w", err)
+	var user *model.User
+	err = s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		var err error
+
+		// Fetch user by ID
+		user, err = tx.GetUserByID(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+		if req.ExpectedVersion != 0 && req.ExpectedVersion != user.Version {
+			return model.ErrVersionConflict
+		}
+
+		// Update the requested fields
+		if fields["name"] {
+			user.Name = req.Name
+		}
+		if fields["streetName"] {
+			user.StreetName = req.StreetName
+		}
+		if fields["locality"] {
+			user.Locality = req.Locality
+		}
+		if fields["state"] {
+			user.State = req.State
+		}
+		if fields["pincode"] {
+			user.Pincode = req.Pincode
+		}
+		if fields["phoneNumber"] {
+			phoneNumber := req.PhoneNumber
+			if phoneNumber != "" {
+				if phoneNumber, err = util.NormalizePhone(phoneNumber, s.phoneRegion); err != nil {
+					return &model.FieldError{Field: "phoneNumber", Err: err}
+				}
+			}
+			// A new number has to be verified again
+			if phoneNumber != user.PhoneNumber {
+				user.PhoneNumber = phoneNumber
+				user.IsPhoneVerified = false
+			}
+		}
+		if fields["state"] || fields["pincode"] {
+			if user.State, err = normalizeAddress(user.Pincode, user.State); err != nil {
+				return err
+			}
+		}
+
+		// Save updated user profile in repository
+		if err := tx.UpdateUser(ctx, user); err != nil {
+			return fmt.Errorf("failed to update profile: %w", err)
+		}
+
+		// Refetch the updated user data to ensure data consistency
+		user, err = tx.GetUserByID(ctx, req.UserId)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, model.ErrVersionConflict) {
+		return nil, s.versionConflict(ctx, req.UserId)
 	}
-
-	// Refetch the updated user data to ensure data consistency
-	user, err = s.repo.GetUserByID(req.UserId)
 	if err != nil {
 		return nil, err
 	}
+	s.evaluateBadges(ctx, req.UserId)
 
 	// Prepare response with updated profile details
 	return &userPb.UpdateProfileResponse{
 		Success: true,
 		Message: "Profile updated successfullyyy",
-		Profile: &userPb.ProfileResponse{
-			UserId:      user.ID,
-			Email:       user.Email,
-			Name:        user.Name,
-			Reputation:  user.Reputation,
-			StreetName:  user.StreetName,
-			Locality:    user.Locality,
-			State:       user.State,
-			Pincode:     user.Pincode,
-			PhoneNumber: user.PhoneNumber,
-			IsVerified:  user.IsVerified,
-			IsBanned:    user.IsBanned,
-		},
+		Profile: toProfileResponse(user),
 	}, nil
 }
 
+func toProfileResponse(user *model.User) *userPb.ProfileResponse {
+	return &userPb.ProfileResponse{
+		UserId:          user.ID,
+		Email:           user.Email,
+		Name:            user.Name,
+		Reputation:      user.Reputation,
+		StreetName:      user.StreetName,
+		Locality:        user.Locality,
+		State:           user.State,
+		Pincode:         user.Pincode,
+		PhoneNumber:     user.PhoneNumber,
+		IsVerified:      user.IsVerified,
+		IsBanned:        user.IsBanned,
+		IsPhoneVerified: user.IsPhoneVerified,
+		Version:         user.Version,
+		Etag:            profileETag(user.Version),
+	}
+}
+
+// updatableProfileFields are the profile fields UpdateProfile can write
+var updatableProfileFields = []string{"name", "streetName", "locality", "state", "pincode", "phoneNumber"}
+
+// profileUpdateFields returns the profile fields an update writes. They are
+// the paths of the update mask or, without one, the non-empty fields.
+// Paths may be given in snake_case, as JSON clients send them.
+func profileUpdateFields(req *userPb.UpdateProfileRequest) (map[string]bool, error) {
+	fields := make(map[string]bool)
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		values := map[string]string{
+			"name":        req.Name,
+			"streetName":  req.StreetName,
+			"locality":    req.Locality,
+			"state":       req.State,
+			"pincode":     req.Pincode,
+			"phoneNumber": req.PhoneNumber,
+		}
+		for field, value := range values {
+			fields[field] = value != ""
+		}
+		return fields, nil
+	}
+
+	profile := (&userPb.ProfileResponse{}).ProtoReflect().Descriptor().Fields()
+	for _, path := range paths {
+		field := lowerCamelCase(path)
+		switch {
+		case slices.Contains(updatableProfileFields, field):
+			fields[field] = true
+		case profile.ByName(protoreflect.Name(field)) != nil:
+			return nil, &model.FieldError{Field: "updateMask", Err: fmt.Errorf("%s: %w", path, model.ErrImmutableField)}
+		default:
+			return nil, &model.FieldError{Field: "updateMask", Err: fmt.Errorf("%s: %w", path, model.ErrUnknownField)}
+		}
+	}
+	return fields, nil
+}
+
+// lowerCamelCase converts a snake_case name to lowerCamelCase
+func lowerCamelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// profileETag is the entity tag of a profile at version
+func profileETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
 func (s *UserService) CheckBan(ctx context.Context, req *userPb.CheckBanRequest) (*userPb.CheckBanResponse, error) {
 
-	status, error := s.repo.CheckBan(req.UserID)
+	status, error := s.repo.CheckBan(ctx, req.UserID)
 
 	return &userPb.CheckBanResponse{
 		BanStatus: status,
@@ -279,14 +503,20 @@ func (s *UserService) BanUser(ctx context.Context, req *userPb.BanUserRequest) (
 		return &userPb.BanUserResponse{
 			Success: false,
 			Message: "User Ban failed",
-		}, errors.New("userId doesnt exist")
+		}, &model.FieldError{Field: "userId", Err: model.ErrRequiredField}
 	}
 
-	if err := s.repo.BanUser(req.UserId); err != nil {
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if err := tx.BanUser(ctx, req.UserId); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, model.AuditActorAdmin, model.AuditActionBan, req.UserId, nil)
+	})
+	if err != nil {
 		return &userPb.BanUserResponse{
 			Success: false,
 			Message: "User Ban failed",
-		}, errors.New(err.Error())
+		}, err
 	}
 
 	return &userPb.BanUserResponse{
@@ -301,14 +531,20 @@ func (s *UserService) UnBanUser(ctx context.Context, req *userPb.UnBanUserReques
 		return &userPb.UnBanUserResponse{
 			Success: false,
 			Message: "User UnBan failed",
-		}, errors.New("userId doesnt exist")
+		}, &model.FieldError{Field: "userId", Err: model.ErrRequiredField}
 	}
 
-	if err := s.repo.UnBanUser(req.UserId); err != nil {
+	err := s.repo.WithinTx(ctx, func(tx repository.UserRepository) error {
+		if err := tx.UnBanUser(ctx, req.UserId); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, model.AuditActorAdmin, model.AuditActionUnban, req.UserId, nil)
+	})
+	if err != nil {
 		return &userPb.UnBanUserResponse{
 			Success: false,
 			Message: "User UnBan failed",
-		}, errors.New(err.Error())
+		}, err
 	}
 
 	return &userPb.UnBanUserResponse{
@@ -316,3 +552,17 @@ func (s *UserService) UnBanUser(ctx context.Context, req *userPb.UnBanUserReques
 		Message: "User UnBanned Succesfully",
 	}, nil
 }
+
+// GetUserPrivileges returns the privileges the user's reputation unlocks
+func (s *UserService) GetUserPrivileges(ctx context.Context, req *userPb.GetUserPrivilegesRequest) (*userPb.GetUserPrivilegesResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.GetUserPrivilegesResponse{
+		UserId:     user.ID,
+		Reputation: user.Reputation,
+		Privileges: model.PrivilegesFor(s.privilegeTiers, user.Reputation),
+	}, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bufbuild/protovalidate-go"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+// UnaryValidationInterceptor rejects requests that break the field rules
+// declared in user.proto before they reach the service. It must run inside
+// UnaryErrorInterceptor so violations are reported as InvalidArgument.
+func UnaryValidationInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if msg, ok := req.(proto.Message); ok {
+		if err := validateMessage(msg); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// StreamValidationInterceptor is UnaryValidationInterceptor for streaming
+// RPCs, checking every message the client sends
+func StreamValidationInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validatingStream{ServerStream: ss})
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if msg, ok := m.(proto.Message); ok {
+		return validateMessage(msg)
+	}
+	return nil
+}
+
+// validateMessage checks msg against the buf.validate rules on its fields
+// with protovalidate, which also descends into message fields, lists and
+// maps. Broken rules are returned as a model.ValidationError; any other
+// error, such as a rule that does not compile, is returned as is.
+func validateMessage(msg proto.Message) error {
+	err := protovalidate.Validate(msg)
+	var invalid *protovalidate.ValidationError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	violations := make([]*model.FieldError, 0, len(invalid.Violations))
+	for _, v := range invalid.Violations {
+		violations = append(violations, &model.FieldError{
+			Field: protovalidate.FieldPathString(v.Proto.GetField()),
+			Err:   violationError(v),
+		})
+	}
+	return &model.ValidationError{Violations: violations}
+}
+
+// violationError reports missing values as model.ErrRequiredField and bad
+// email addresses as model.ErrInvalidEmail, so they carry the same reasons
+// as the checks in the service, and any other violation by its message
+func violationError(v *protovalidate.Violation) error {
+	switch v.Proto.GetConstraintId() {
+	case "required":
+		return model.ErrRequiredField
+	case "string.min_len":
+		if v.FieldValue.IsValid() && v.FieldValue.String() == "" {
+			return model.ErrRequiredField
+		}
+	case "string.email", "string.email_empty":
+		return model.ErrInvalidEmail
+	}
+	return errors.New(v.Proto.GetMessage())
+}
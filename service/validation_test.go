@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+	userPb "github.com/liju-github/EcommerceUserService/proto/user"
+)
+
+func TestValidateMessage(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("x", n) }
+	address := &userPb.Address{Label: "Home", StreetName: "1 Main St", Pincode: "560001"}
+
+	tests := []struct {
+		name string
+		req  proto.Message
+		// the fields reported as violations, none for a valid request
+		wantFields []string
+	}{
+		{"register", &userPb.RegisterRequest{Email: "jane@example.com", Password: "password1", Name: "Jane"}, nil},
+		{"register invalid", &userPb.RegisterRequest{Email: "jane", Password: "short", Name: "", StreetName: long(201)},
+			[]string{"email", "password", "name", "streetName"}},
+		{"register password over bcrypt limit", &userPb.RegisterRequest{Email: "jane@example.com", Password: long(73), Name: "Jane"},
+			[]string{"password"}},
+		{"login", &userPb.LoginRequest{Email: "jane@example.com", Password: "x"}, nil},
+		{"login empty", &userPb.LoginRequest{}, []string{"email", "password"}},
+		{"verify email", &userPb.EmailVerificationRequest{UserId: "usr_1", VerificationCode: "123456"}, nil},
+		{"verify email code too long", &userPb.EmailVerificationRequest{UserId: "usr_1", VerificationCode: long(17)},
+			[]string{"verificationCode"}},
+		{"profile", &userPb.ProfileRequest{UserId: "usr_1"}, nil},
+		{"profile without user", &userPb.ProfileRequest{}, []string{"userId"}},
+		{"update profile", &userPb.UpdateProfileRequest{UserId: "usr_1", Name: "Jane", ExpectedVersion: 3}, nil},
+		{"update profile invalid", &userPb.UpdateProfileRequest{UserId: long(65), Pincode: long(11), ExpectedVersion: -1},
+			[]string{"userId", "pincode", "expectedVersion"}},
+		{"user by token", &userPb.GetUserByTokenRequest{Token: "token"}, nil},
+		{"user by token empty", &userPb.GetUserByTokenRequest{}, []string{"token"}},
+		{"check ban", &userPb.CheckBanRequest{UserID: "usr_1"}, nil},
+		{"check ban without user", &userPb.CheckBanRequest{}, []string{"userID"}},
+		{"privileges", &userPb.GetUserPrivilegesRequest{UserId: "usr_1"}, nil},
+		{"privileges without user", &userPb.GetUserPrivilegesRequest{}, []string{"userId"}},
+		{"reputation event", &userPb.RecordReputationEventRequest{UserId: "usr_1", Delta: -2, Reason: "downvote"}, nil},
+		{"reputation event zero delta", &userPb.RecordReputationEventRequest{UserId: "usr_1"}, []string{"delta"}},
+		{"reputation event delta too high", &userPb.RecordReputationEventRequest{UserId: "usr_1", Delta: 100000},
+			[]string{"delta"}},
+		{"reputation event delta too low", &userPb.RecordReputationEventRequest{UserId: "usr_1", Delta: -1001},
+			[]string{"delta"}},
+		{"leaderboard", &userPb.GetLeaderboardRequest{}, nil},
+		{"leaderboard period", &userPb.GetLeaderboardRequest{Period: "week", PageSize: 10}, nil},
+		{"leaderboard invalid", &userPb.GetLeaderboardRequest{Period: "decade", PageSize: -1}, []string{"period", "pageSize"}},
+		{"badges", &userPb.ListBadgesRequest{Tier: "gold"}, nil},
+		{"badges unknown tier", &userPb.ListBadgesRequest{Tier: "platinum"}, []string{"tier"}},
+		{"user badges", &userPb.GetUserBadgesRequest{UserId: "usr_1"}, nil},
+		{"user badges without user", &userPb.GetUserBadgesRequest{}, []string{"userId"}},
+		{"add address", &userPb.AddAddressRequest{UserId: "usr_1", Address: address}, nil},
+		{"add address missing", &userPb.AddAddressRequest{UserId: "usr_1"}, []string{"address"}},
+		{"add address invalid", &userPb.AddAddressRequest{UserId: "usr_1", Address: &userPb.Address{Landmark: long(101)}},
+			[]string{"address.landmark"}},
+		{"update address", &userPb.UpdateAddressRequest{UserId: "usr_1", Address: address}, nil},
+		{"update address missing", &userPb.UpdateAddressRequest{}, []string{"userId", "address"}},
+		{"delete address", &userPb.DeleteAddressRequest{UserId: "usr_1", AddressId: "addr_1"}, nil},
+		{"delete address without id", &userPb.DeleteAddressRequest{UserId: "usr_1"}, []string{"addressId"}},
+		{"list addresses", &userPb.ListAddressesRequest{UserId: "usr_1"}, nil},
+		{"list addresses without user", &userPb.ListAddressesRequest{}, []string{"userId"}},
+		{"default address", &userPb.SetDefaultAddressRequest{UserId: "usr_1", AddressId: "addr_1", Usage: "billing"}, nil},
+		{"default address unknown usage", &userPb.SetDefaultAddressRequest{UserId: "usr_1", AddressId: "addr_1", Usage: "gifts"},
+			[]string{"usage"}},
+		{"pincode", &userPb.LookupPincodeRequest{Pincode: "560001"}, nil},
+		{"pincode empty", &userPb.LookupPincodeRequest{}, []string{"pincode"}},
+		{"send phone otp", &userPb.SendPhoneOTPRequest{UserId: "usr_1", PhoneNumber: "+919876543210"}, nil},
+		{"send phone otp number too long", &userPb.SendPhoneOTPRequest{UserId: "usr_1", PhoneNumber: long(21)},
+			[]string{"phoneNumber"}},
+		{"verify phone otp", &userPb.VerifyPhoneOTPRequest{UserId: "usr_1", Code: "123456"}, nil},
+		{"verify phone otp without code", &userPb.VerifyPhoneOTPRequest{UserId: "usr_1"}, []string{"code"}},
+		{"email change", &userPb.RequestEmailChangeRequest{UserId: "usr_1", NewEmail: "new@example.com"}, nil},
+		{"email change invalid email", &userPb.RequestEmailChangeRequest{UserId: "usr_1", NewEmail: "Jane <new@example.com>"},
+			[]string{"newEmail"}},
+		{"confirm email change", &userPb.ConfirmEmailChangeRequest{UserId: "usr_1", Code: "123456"}, nil},
+		{"confirm email change without code", &userPb.ConfirmEmailChangeRequest{UserId: "usr_1"}, []string{"code"}},
+		{"cancel email change", &userPb.CancelEmailChangeRequest{UserId: "usr_1", CancelToken: "token"}, nil},
+		{"cancel email change token too long", &userPb.CancelEmailChangeRequest{UserId: "usr_1", CancelToken: long(257)},
+			[]string{"cancelToken"}},
+		{"delete account", &userPb.DeleteAccountRequest{UserId: "usr_1"}, nil},
+		{"delete account without user", &userPb.DeleteAccountRequest{}, []string{"userId"}},
+		{"restore account", &userPb.RestoreAccountRequest{UserId: "usr_1"}, nil},
+		{"restore account without user", &userPb.RestoreAccountRequest{}, []string{"userId"}},
+		{"export", &userPb.ExportUserDataRequest{UserId: "usr_1"}, nil},
+		{"export without user", &userPb.ExportUserDataRequest{}, []string{"userId"}},
+		{"record consent", &userPb.RecordConsentRequest{UserId: "usr_1", PolicyType: "marketing", Version: "2"}, nil},
+		{"record consent unknown policy", &userPb.RecordConsentRequest{UserId: "usr_1", PolicyType: "cookies"},
+			[]string{"policyType"}},
+		{"withdraw consent", &userPb.WithdrawConsentRequest{UserId: "usr_1", PolicyType: "marketing"}, nil},
+		{"withdraw consent without policy", &userPb.WithdrawConsentRequest{UserId: "usr_1"}, []string{"policyType"}},
+		{"consents", &userPb.GetConsentsRequest{UserId: "usr_1"}, nil},
+		{"consents without user", &userPb.GetConsentsRequest{}, []string{"userId"}},
+		{"ban", &userPb.BanUserRequest{UserId: "usr_1"}, nil},
+		{"ban without user", &userPb.BanUserRequest{}, []string{"userId"}},
+		{"unban", &userPb.UnBanUserRequest{UserId: "usr_1"}, nil},
+		{"unban without user", &userPb.UnBanUserRequest{}, []string{"userId"}},
+		{"all users", &userPb.GetAllUsersRequest{}, nil},
+	}
+
+	tested := map[protoreflect.FullName]bool{}
+	for _, tt := range tests {
+		tested[tt.req.ProtoReflect().Descriptor().FullName()] = true
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMessage(tt.req)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("validateMessage() = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *model.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("validateMessage() = %v, want a ValidationError", err)
+			}
+			if !errors.Is(err, model.ErrInvalidRequest) {
+				t.Errorf("validateMessage() = %v, want it to wrap %v", err, model.ErrInvalidRequest)
+			}
+			var fields []string
+			for _, violation := range validationErr.Violations {
+				fields = append(fields, violation.Field)
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("violations on %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+
+	// Every RPC's request message has cases above
+	methods := userPb.File_user_user_proto.Services().ByName("UserService").Methods()
+	for i := 0; i < methods.Len(); i++ {
+		if input := methods.Get(i).Input(); !tested[input.FullName()] {
+			t.Errorf("no test cases for %s", input.FullName())
+		}
+	}
+}
+
+func TestValidateMessageDescendsIntoLists(t *testing.T) {
+	resp := &userPb.ListAddressesResponse{Addresses: []*userPb.Address{
+		{Label: "Home"},
+		{Label: strings.Repeat("x", 51)},
+	}}
+
+	var validationErr *model.ValidationError
+	if err := validateMessage(resp); !errors.As(err, &validationErr) {
+		t.Fatalf("validateMessage() = %v, want a ValidationError", err)
+	}
+	if len(validationErr.Violations) != 1 || validationErr.Violations[0].Field != "addresses[1].label" {
+		t.Errorf("violations = %v, want one on addresses[1].label", validationErr.Violations)
+	}
+}
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// ID formats selectable with ID_FORMAT
+const (
+	IDFormatULID   = "ulid"
+	IDFormatUUIDv7 = "uuidv7"
+)
+
+// IDGenerator creates unique, time-sortable record IDs such as
+// "usr_01J9Z3K6Q8R2T4V6X8Z0B2D4F6"
+type IDGenerator interface {
+	NewID(prefix string) string
+}
+
+// NewIDGenerator returns the generator for format, "ulid" or "uuidv7"
+func NewIDGenerator(format string) (IDGenerator, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case IDFormatULID:
+		return ULIDGenerator{}, nil
+	case IDFormatUUIDv7:
+		return UUIDv7Generator{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ID format %q, expected %s or %s", format, IDFormatULID, IDFormatUUIDv7)
+	}
+}
+
+// ULIDGenerator creates lexicographically sortable ULIDs. IDs made in the
+// same millisecond by one process are monotonic; across processes 80 random
+// bits keep them apart.
+type ULIDGenerator struct{}
+
+func (ULIDGenerator) NewID(prefix string) string {
+	return prefix + ulid.Make().String()
+}
+
+// UUIDv7Generator creates time-ordered version 7 UUIDs
+type UUIDv7Generator struct{}
+
+func (UUIDv7Generator) NewID(prefix string) string {
+	return prefix + uuid.Must(uuid.NewV7()).String()
+}
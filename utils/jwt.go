@@ -10,10 +10,11 @@ import (
 
 // CustomClaims extends jwt.StandardClaims
 type CustomClaims struct {
-	UserID     string `json:"userid"`
-	Email      string `json:"email"`
-	Role       string `json:"role"`
-	Reputation int32  `json:"reputation"`
+	UserID     string   `json:"userid"`
+	Email      string   `json:"email"`
+	Role       string   `json:"role"`
+	Reputation int32    `json:"reputation"`
+	Privileges []string `json:"privileges"`
 	jwt.RegisteredClaims
 }
 
@@ -28,7 +29,29 @@ func SetJWTSecretKey(secret string) {
 	JWTSecretKey = secret
 }
 
+// GenerateToken issues a signed JWT for the user carrying their reputation
+// and the privileges it unlocks
+func GenerateToken(user *model.User, role string, privileges []string) (string, error) {
+	now := time.Now()
+	claims := CustomClaims{
+		UserID:     user.ID,
+		Email:      user.Email,
+		Role:       role,
+		Reputation: user.Reputation,
+		Privileges: privileges,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenExpiry)),
+		},
+	}
 
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(JWTSecretKey))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", model.ErrTokenGeneration, err)
+	}
+	return token, nil
+}
 
 // ValidateToken verifies the JWT token
 func ValidateToken(tokenString string) (*CustomClaims, error) {
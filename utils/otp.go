@@ -0,0 +1,41 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// GenerateOTP returns a random numeric one-time code with the given number of digits
+func GenerateOTP(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+// GenerateSecret returns n random bytes encoded as hex, for links and
+// tokens that are too long to type
+func GenerateSecret(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashOTP returns the hex SHA-256 digest a one-time code is stored as
+func HashOTP(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckOTP reports whether code matches a digest produced by HashOTP
+func CheckOTP(code, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashOTP(code)), []byte(hash)) == 1
+}
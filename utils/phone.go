@@ -0,0 +1,21 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+
+	model "github.com/liju-github/EcommerceUserService/models"
+)
+
+// NormalizePhone parses a phone number written in international or national
+// format and returns it in E.164 form, e.g. "+919876543210". National numbers
+// are interpreted in defaultRegion. Numbers that are not valid for their
+// region according to libphonenumber's metadata are rejected.
+func NormalizePhone(raw, defaultRegion string) (string, error) {
+	number, err := phonenumbers.Parse(raw, strings.ToUpper(defaultRegion))
+	if err != nil || !phonenumbers.IsValidNumber(number) {
+		return "", model.ErrInvalidPhoneNumber
+	}
+	return phonenumbers.Format(number, phonenumbers.E164), nil
+}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Run calls job immediately and then once per interval until ctx is
+// cancelled. Failures are logged and retried on the next tick.
+func Run(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			log.Printf("%s failed: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("%s stopped", name)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Group starts jobs with Run and waits for all of them to stop.
+type Group struct {
+	wg sync.WaitGroup
+}
+
+// Go runs job in the background with Run.
+func (g *Group) Go(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		Run(ctx, name, interval, job)
+	}()
+}
+
+// Wait blocks until every job has stopped, after their context is cancelled.
+func (g *Group) Wait() {
+	g.wg.Wait()
+}